@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Lolozendev/CTFManager/internal/app/ad"
+	"github.com/Lolozendev/CTFManager/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// serviceStatus is the view of a team's service in structured output
+type serviceStatus struct {
+	Team    string  `json:"team" yaml:"team"`
+	Service string  `json:"service" yaml:"service"`
+	SLA     float64 `json:"sla" yaml:"sla"`
+
+	ad.ServiceStatus `yaml:",inline"`
+}
+
+func adCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ad",
+		Short: "Attack-defense event operations",
+	}
+
+	cmd.AddCommand(adStatusCmd())
+
+	return cmd
+}
+
+func adStatusCmd() *cobra.Command {
+	var (
+		teamName string
+		format   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the last check status and SLA of every team's services",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			outputFormat, err := output.ParseFormat(format)
+			if err != nil {
+				return err
+			}
+
+			sla, err := ad.New(cfg, log).Status()
+			if err != nil {
+				return err
+			}
+
+			var statuses []serviceStatus
+			for name, services := range sla.Teams {
+				if teamName != "" && name != teamName {
+					continue
+				}
+				for service, status := range services {
+					status.Flags = nil // Planted flags are secrets
+					statuses = append(statuses, serviceStatus{
+						Team:          name,
+						Service:       service,
+						SLA:           status.SLA(),
+						ServiceStatus: *status,
+					})
+				}
+			}
+			sort.Slice(statuses, func(i, j int) bool {
+				if statuses[i].Team != statuses[j].Team {
+					return statuses[i].Team < statuses[j].Team
+				}
+				return statuses[i].Service < statuses[j].Service
+			})
+
+			if len(statuses) == 0 && !outputFormat.Structured() {
+				log.Info("No service checked yet, is the daemon running?")
+				return nil
+			}
+
+			if !outputFormat.Structured() {
+				fmt.Printf("\nTick %d\n", sla.Tick)
+			}
+			return printOutput(outputFormat, statuses, func(wide bool) output.Table {
+				table := output.Table{Headers: []string{"TEAM", "SERVICE", "STATUS", "SLA", "CHECKED"}}
+				if wide {
+					table.Headers = append(table.Headers, "MESSAGE")
+				}
+
+				for _, s := range statuses {
+					row := []string{
+						s.Team,
+						s.Service,
+						string(s.Status),
+						fmt.Sprintf("%.1f%% (%d/%d)", s.SLA*100, s.Up, s.Ticks),
+						s.Checked.Local().Format(time.DateTime),
+					}
+					if wide {
+						row = append(row, orDash(s.Message))
+					}
+					table.Rows = append(table.Rows, row)
+				}
+
+				return table
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&teamName, "team", "t", "", "Only show the given team")
+	addOutputFlag(cmd, &format)
+
+	return cmd
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Lolozendev/CTFManager/internal/audit"
+	"github.com/Lolozendev/CTFManager/internal/output"
+	"github.com/Lolozendev/CTFManager/internal/store"
+	"github.com/spf13/cobra"
+)
+
+// auditSubject names the team and challenge affected by a command
+type auditSubject struct {
+	Team      string
+	Challenge string
+	NewTeam   string // Name of the team after the command, when it renames it
+}
+
+// auditTarget returns the subject of a command from its arguments
+type auditTarget func(args []string) auditSubject
+
+func teamArg(i int) auditTarget {
+	return func(args []string) auditSubject {
+		return auditSubject{Team: args[i]}
+	}
+}
+
+func challengeArg(i int) auditTarget {
+	return func(args []string) auditSubject {
+		return auditSubject{Challenge: args[i]}
+	}
+}
+
+// renamedTeamArg is the target of a command renaming team args[i] to args[j]
+func renamedTeamArg(i, j int) auditTarget {
+	return func(args []string) auditSubject {
+		return auditSubject{Team: args[i], NewTeam: args[j]}
+	}
+}
+
+// audited wraps a mutating command so that its outcome, along with the state of
+// the affected team or challenge before and after, is recorded in the audit log
+func audited(action string, target auditTarget, run func(*cobra.Command, []string) error) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		var subject auditSubject
+		if target != nil {
+			subject = target(args)
+		}
+
+		st := store.FromConfig(cfg)
+		before := snapshotTarget(st, subject.Team, subject.Challenge)
+
+		err := run(cmd, args)
+
+		// A renamed team is found under its new name afterwards
+		afterTeam := subject.Team
+		if err == nil && subject.NewTeam != "" {
+			afterTeam = subject.NewTeam
+		}
+
+		entry := audit.Entry{
+			Action:    action,
+			Team:      subject.Team,
+			Challenge: subject.Challenge,
+			Before:    before,
+			After:     snapshotTarget(st, afterTeam, subject.Challenge),
+			Result:    audit.ResultSuccess,
+		}
+		if err != nil {
+			entry.Result = audit.ResultFailure
+			entry.Error = err.Error()
+		}
+
+		if auditErr := audit.New(cfg.GetDataPath(audit.FileName)).Record(entry); auditErr != nil {
+			log.Warn("Failed to record audit entry", "error", auditErr)
+		}
+
+		return err
+	}
+}
+
+// snapshotTarget returns the persisted state of a team or challenge
+func snapshotTarget(st *store.Store, teamName string, challengeName string) []byte {
+	if teamName == "" && challengeName == "" {
+		return nil
+	}
+
+	state, err := st.Load()
+	if err != nil {
+		return nil
+	}
+
+	if teamName != "" {
+		return audit.Snapshot(state.Team(teamName))
+	}
+	return audit.Snapshot(state.Challenge(challengeName))
+}
+
+// auditCmd queries the audit log
+func auditCmd() *cobra.Command {
+	var (
+		filter audit.Filter
+		since  string
+		format string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Show the audit log of administrative actions",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			outputFormat, err := output.ParseFormat(format)
+			if err != nil {
+				return err
+			}
+
+			if since != "" {
+				t, err := parseSince(since, time.Now())
+				if err != nil {
+					return err
+				}
+				filter.Since = t
+			}
+
+			entries, err := audit.New(cfg.GetDataPath(audit.FileName)).Query(filter)
+			if err != nil {
+				return err
+			}
+
+			if len(entries) == 0 && !outputFormat.Structured() {
+				log.Info("No audit entries found")
+				return nil
+			}
+			if entries == nil {
+				entries = []audit.Entry{}
+			}
+
+			return printOutput(outputFormat, entries, func(wide bool) output.Table {
+				table := output.Table{Headers: []string{"TIME", "USER", "ACTION", "TEAM", "CHALLENGE", "RESULT"}}
+				if wide {
+					table.Headers = append(table.Headers, "COMMAND")
+				}
+
+				for _, e := range entries {
+					result := e.Result
+					if e.Error != "" {
+						result += ": " + e.Error
+					}
+
+					row := []string{
+						e.Time.Local().Format(time.DateTime), e.User, e.Action,
+						orDash(e.Team), orDash(e.Challenge), result,
+					}
+					if wide {
+						row = append(row, e.Command)
+					}
+					table.Rows = append(table.Rows, row)
+				}
+
+				return table
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&filter.Team, "team", "t", "", "Only show actions on the given team")
+	cmd.Flags().StringVar(&filter.Challenge, "challenge", "", "Only show actions on the given challenge")
+	cmd.Flags().StringVar(&filter.Action, "action", "", "Only show the given action (e.g. team, team.delete)")
+	cmd.Flags().StringVarP(&since, "since", "s", "", "Only show actions since a time (RFC 3339, YYYY-MM-DD) or a duration ago (e.g. 2h)")
+	addOutputFlag(cmd, &format)
+
+	return cmd
+}
+
+// parseSince accepts a duration relative to now, an RFC 3339 time or a date
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateTime, value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since value %q (expected a duration, an RFC 3339 time or a date)", value)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
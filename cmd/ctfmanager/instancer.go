@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Lolozendev/CTFManager/internal/app/instancer"
+	"github.com/Lolozendev/CTFManager/internal/app/team"
+	"github.com/Lolozendev/CTFManager/internal/model"
+	"github.com/Lolozendev/CTFManager/internal/output"
+	"github.com/spf13/cobra"
+)
+
+func instancerCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "instancer",
+		Short: "Manage on-demand challenge instances",
+	}
+
+	cmd.AddCommand(instancerTokenCmd())
+	cmd.AddCommand(instancerListCmd())
+	cmd.AddCommand(instancerStopCmd())
+
+	return cmd
+}
+
+func instancerTokenCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "token <team>",
+		Short: "Print the instancer API token of a team",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			teams, err := team.New(cfg, log).List()
+			if err != nil {
+				return err
+			}
+			if !slices.ContainsFunc(teams, func(t model.Team) bool { return t.Name == args[0] && t.Enabled }) {
+				return fmt.Errorf("enabled team %s not found", args[0])
+			}
+
+			token, err := instancer.New(cfg, log).Token(args[0])
+			if err != nil {
+				return err
+			}
+
+			fmt.Println(token)
+			return nil
+		},
+	}
+}
+
+func instancerListCmd() *cobra.Command {
+	var (
+		teamName string
+		format   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List running on-demand instances",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			outputFormat, err := output.ParseFormat(format)
+			if err != nil {
+				return err
+			}
+
+			instances, err := instancer.New(cfg, log).List(teamName)
+			if err != nil {
+				return err
+			}
+
+			if len(instances) == 0 && !outputFormat.Structured() {
+				log.Info("No running instance")
+				return nil
+			}
+
+			now := time.Now()
+			return printOutput(outputFormat, instances, func(wide bool) output.Table {
+				table := output.Table{Headers: []string{"TEAM", "CHALLENGE", "ADDRESS", "EXPIRES IN"}}
+				if wide {
+					table.Headers = append(table.Headers, "STARTED")
+				}
+
+				for _, inst := range instances {
+					expires := inst.Expires.Sub(now).Round(time.Second).String()
+					if inst.Pending {
+						expires = "starting"
+					}
+
+					row := []string{inst.Team, inst.Challenge, inst.Address, expires}
+					if wide {
+						row = append(row, inst.Started.Local().Format(time.DateTime))
+					}
+					table.Rows = append(table.Rows, row)
+				}
+				return table
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&teamName, "team", "t", "", "Only list the instances of the given team")
+	addOutputFlag(cmd, &format)
+
+	return cmd
+}
+
+func instancerStopCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "stop <team> <challenge>",
+		Short: "Stop the on-demand instance of a challenge of a team",
+		Args:  cobra.ExactArgs(2),
+		RunE: audited("instancer.stop", teamArg(0), func(cmd *cobra.Command, args []string) error {
+			teams, err := team.New(cfg, log).List()
+			if err != nil {
+				return err
+			}
+
+			for _, t := range teams {
+				if t.Name != args[0] {
+					continue
+				}
+				if err := instancer.New(cfg, log).Stop(cmd.Context(), t, args[1]); err != nil {
+					return err
+				}
+
+				fmt.Printf("\n✓ Instance of '%s' stopped for team '%s'\n\n", args[1], args[0])
+				return nil
+			}
+
+			return fmt.Errorf("team %s not found", args[0])
+		}),
+	}
+}
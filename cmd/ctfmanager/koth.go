@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Lolozendev/CTFManager/internal/app/deploy"
+	"github.com/Lolozendev/CTFManager/internal/app/koth"
+	"github.com/Lolozendev/CTFManager/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// hillScore is the view of a team's king-of-the-hill score in structured output
+type hillScore struct {
+	Team   string `json:"team" yaml:"team"`
+	Points int    `json:"points" yaml:"points"`
+	Rounds int    `json:"rounds" yaml:"rounds"` // Rounds the team held the hill
+}
+
+func kothCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "koth",
+		Short: "King-of-the-hill event operations",
+	}
+
+	cmd.AddCommand(kothDeployCmd())
+	cmd.AddCommand(kothStatusCmd())
+	cmd.AddCommand(kothHistoryCmd())
+
+	return cmd
+}
+
+func kothDeployCmd() *cobra.Command {
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "deploy",
+		Short: "Deploy the contested challenge on the game network",
+		Args:  cobra.NoArgs,
+		RunE: audited("koth.deploy", nil, func(cmd *cobra.Command, args []string) error {
+			if err := koth.New(cfg, log).Deploy(cmd.Context(), force); err != nil {
+				if errors.Is(err, deploy.ErrOutsideEvent) {
+					return fmt.Errorf("%w (use --force to deploy anyway)", err)
+				}
+				return err
+			}
+
+			fmt.Printf("\n✓ Hill '%s' deployed at %s\n\n", cfg.KingOfTheHill.Challenge, cfg.GameNetwork().HillIP())
+			return nil
+		}),
+	}
+
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Deploy even outside the event window")
+
+	return cmd
+}
+
+func kothStatusCmd() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the king-of-the-hill scores and current owner",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			outputFormat, err := output.ParseFormat(format)
+			if err != nil {
+				return err
+			}
+
+			history, err := koth.New(cfg, log).History()
+			if err != nil {
+				return err
+			}
+
+			held := make(map[string]int)
+			for _, r := range history.Rounds {
+				if r.Owner != "" {
+					held[r.Owner]++
+				}
+			}
+
+			scores := make([]hillScore, 0, len(history.Scores))
+			for name, points := range history.Scores {
+				scores = append(scores, hillScore{Team: name, Points: points, Rounds: held[name]})
+			}
+			sort.Slice(scores, func(i, j int) bool {
+				if scores[i].Points != scores[j].Points {
+					return scores[i].Points > scores[j].Points
+				}
+				return scores[i].Team < scores[j].Team
+			})
+
+			if !outputFormat.Structured() {
+				owner := "nobody"
+				if n := len(history.Rounds); n > 0 && history.Rounds[n-1].Owner != "" {
+					owner = history.Rounds[n-1].Owner
+				}
+				fmt.Printf("\nTick %d, held by %s\n", history.Tick(), owner)
+			}
+
+			return printOutput(outputFormat, scores, func(wide bool) output.Table {
+				table := output.Table{Headers: []string{"TEAM", "POINTS", "ROUNDS"}}
+				for _, s := range scores {
+					table.Rows = append(table.Rows, []string{s.Team, strconv.Itoa(s.Points), strconv.Itoa(s.Rounds)})
+				}
+				return table
+			})
+		},
+	}
+
+	addOutputFlag(cmd, &format)
+
+	return cmd
+}
+
+func kothHistoryCmd() *cobra.Command {
+	var (
+		limit  int
+		format string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Show who held the hill at each tick",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			outputFormat, err := output.ParseFormat(format)
+			if err != nil {
+				return err
+			}
+
+			history, err := koth.New(cfg, log).History()
+			if err != nil {
+				return err
+			}
+
+			rounds := history.Rounds
+			if limit > 0 && len(rounds) > limit {
+				rounds = rounds[len(rounds)-limit:]
+			}
+
+			return printOutput(outputFormat, rounds, func(wide bool) output.Table {
+				table := output.Table{Headers: []string{"TICK", "TIME", "OWNER", "POINTS"}}
+				if wide {
+					table.Headers = append(table.Headers, "ERROR")
+				}
+
+				for _, r := range rounds {
+					row := []string{strconv.Itoa(r.Tick), r.Time.Local().Format(time.DateTime), orDash(r.Owner), strconv.Itoa(r.Points)}
+					if wide {
+						row = append(row, orDash(r.Error))
+					}
+					table.Rows = append(table.Rows, row)
+				}
+				return table
+			})
+		},
+	}
+
+	cmd.Flags().IntVarP(&limit, "limit", "n", 20, "Show only the last n rounds (0 for all)")
+	addOutputFlag(cmd, &format)
+
+	return cmd
+}
@@ -1,29 +1,49 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/Lolozendev/CTFManager/internal/app/ad"
+	"github.com/Lolozendev/CTFManager/internal/app/backup"
 	"github.com/Lolozendev/CTFManager/internal/app/challenge"
-	"github.com/Lolozendev/CTFManager/internal/app/compose"
+	"github.com/Lolozendev/CTFManager/internal/app/deploy"
+	"github.com/Lolozendev/CTFManager/internal/app/event"
+	"github.com/Lolozendev/CTFManager/internal/app/health"
+	"github.com/Lolozendev/CTFManager/internal/app/instancer"
+	"github.com/Lolozendev/CTFManager/internal/app/koth"
+	"github.com/Lolozendev/CTFManager/internal/app/metrics"
+	"github.com/Lolozendev/CTFManager/internal/app/migrate"
+	"github.com/Lolozendev/CTFManager/internal/app/reset"
+	"github.com/Lolozendev/CTFManager/internal/app/scheduler"
 	"github.com/Lolozendev/CTFManager/internal/app/team"
 	"github.com/Lolozendev/CTFManager/internal/config"
 	"github.com/Lolozendev/CTFManager/internal/logger"
 	"github.com/Lolozendev/CTFManager/internal/model"
+	"github.com/Lolozendev/CTFManager/internal/output"
+	"github.com/Lolozendev/CTFManager/internal/store"
 	"github.com/spf13/cobra"
 )
 
 var (
-	cfg *config.Config
-	log = logger.Get()
+	cfg        *config.Config
+	configPath string
+	log        = logger.Get()
 )
 
 func main() {
 	defer logger.Close()
 
-	// Initialize configuration
-	cfg = config.Default()
+	logOptions := logger.DefaultOptions()
 
 	// Create root command
 	rootCmd := &cobra.Command{
@@ -31,12 +51,42 @@ func main() {
 		Short: "CTFManager - Manage dockerized CTF environments",
 		Long: `CTFManager is a CLI tool for managing Docker-based CTF (Capture The Flag) environments.
 It helps you create and manage teams, challenges, and their associated infrastructure.`,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := logger.Configure(logOptions); err != nil {
+				return err
+			}
+
+			// Initialize configuration
+			var err error
+			cfg, err = config.Load(configPath)
+			return err
+		},
 	}
 
+	flags := rootCmd.PersistentFlags()
+	flags.StringVarP(&configPath, "config", "c", config.DefaultPath, "Configuration file")
+	flags.StringVar(&logOptions.Level, "log-level", logOptions.Level, "Log level (debug, info, warn, error)")
+	flags.StringVar(&logOptions.Format, "log-format", logOptions.Format, "Log format (text, json, logfmt)")
+	flags.StringVar(&logOptions.File, "log-file", logOptions.File, "Log file, or 'none' to only log to stderr")
+	flags.IntVar(&logOptions.MaxSizeMB, "log-max-size", logOptions.MaxSizeMB, "Rotate the log file after this many megabytes (0 to disable)")
+	flags.IntVar(&logOptions.MaxBackups, "log-max-backups", logOptions.MaxBackups, "Number of rotated log files to keep")
+
 	// Add subcommands
 	rootCmd.AddCommand(setupCmd())
+	rootCmd.AddCommand(migrateCmd())
 	rootCmd.AddCommand(teamCmd())
 	rootCmd.AddCommand(challengeCmd())
+	rootCmd.AddCommand(checkCmd())
+	rootCmd.AddCommand(eventCmd())
+	rootCmd.AddCommand(auditCmd())
+	rootCmd.AddCommand(undoCmd())
+	rootCmd.AddCommand(backupCmd())
+	rootCmd.AddCommand(restoreCmd())
+	rootCmd.AddCommand(adCmd())
+	rootCmd.AddCommand(kothCmd())
+	rootCmd.AddCommand(instancerCmd())
+	rootCmd.AddCommand(networkCmd())
+	rootCmd.AddCommand(daemonCmd())
 
 	if err := rootCmd.Execute(); err != nil {
 		log.Error("Command failed", "error", err)
@@ -49,19 +99,67 @@ func setupCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "setup",
 		Short: "Initialize the CTF environment",
-		RunE: func(cmd *cobra.Command, args []string) error {
+		RunE: audited("setup", nil, func(cmd *cobra.Command, args []string) error {
 			log.Info("Setting up CTF environment...")
 
 			if err := cfg.Validate(); err != nil {
 				return fmt.Errorf("configuration validation failed: %w", err)
 			}
 
+			// Import existing directory layouts on first setup
+			if !store.FromConfig(cfg).Exists() {
+				report, err := migrate.New(cfg, log).Run(false)
+				if err != nil {
+					return fmt.Errorf("failed to initialize state: %w", err)
+				}
+				printMigrationReport(report)
+			}
+
+			// Overlapping networks only break deployments, warn about them early
+			teams, _ := team.New(cfg, log).List()
+			if conflicts, err := deploy.New(cfg, log).Conflicts(cmd.Context(), teams); err != nil {
+				log.Warn("Network overlaps not checked", "error", err)
+			} else {
+				for _, c := range conflicts {
+					log.Warn("Network overlaps a managed network", "network", c.Network, "subnet", c.Subnet,
+						"source", c.Source, "name", c.Name, "overlap", c.Overlap)
+				}
+			}
+
 			log.Info("CTF environment setup complete!")
 			return nil
-		},
+		}),
 	}
 }
 
+// migrateCmd imports legacy directory layouts into the state store
+func migrateCmd() *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Import existing team and challenge directories into the state store",
+		Long: `Import team and challenge directories named <id>-<name> or x-<name> into the
+state store. Directories are kept as they are; only unregistered ones are imported.`,
+		RunE: audited("migrate", nil, func(cmd *cobra.Command, args []string) error {
+			report, err := migrate.New(cfg, log).Run(dryRun)
+			if err != nil {
+				return err
+			}
+
+			printMigrationReport(report)
+			if dryRun {
+				fmt.Print("Dry run, nothing was saved\n\n")
+			}
+			return nil
+		}),
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be imported without saving")
+
+	return cmd
+}
+
 // teamCmd returns the team management command
 func teamCmd() *cobra.Command {
 	cmd := &cobra.Command{
@@ -72,41 +170,75 @@ func teamCmd() *cobra.Command {
 	cmd.AddCommand(teamListCmd())
 	cmd.AddCommand(teamCreateCmd())
 	cmd.AddCommand(teamDeleteCmd())
+	cmd.AddCommand(teamRenameCmd())
+	cmd.AddCommand(teamMoveCmd())
+	cmd.AddCommand(teamPlaceCmd())
 	cmd.AddCommand(teamEnableCmd())
 	cmd.AddCommand(teamDisableCmd())
+	cmd.AddCommand(teamDeployCmd())
 
 	return cmd
 }
 
 func teamListCmd() *cobra.Command {
-	return &cobra.Command{
+	var format string
+
+	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List all teams",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			outputFormat, err := output.ParseFormat(format)
+			if err != nil {
+				return err
+			}
+
 			mgr := team.New(cfg, log)
 			teams, err := mgr.List()
 			if err != nil {
 				return err
 			}
 
-			if len(teams) == 0 {
+			if len(teams) == 0 && !outputFormat.Structured() {
 				log.Info("No teams found")
 				return nil
 			}
 
-			fmt.Println("\nTeams:")
-			for _, t := range teams {
-				status := "enabled"
-				if !t.Enabled {
-					status = "disabled"
+			return printOutput(outputFormat, teams, func(wide bool) output.Table {
+				table := output.Table{Headers: []string{"ID", "NAME", "STATUS"}}
+				if wide {
+					table.Headers = append(table.Headers, "HOST", "MEMBERS", "PATH")
 				}
-				fmt.Printf("  [%d] %s (%s)\n", t.ID, t.Name, status)
-			}
-			fmt.Println()
 
-			return nil
+				for _, t := range teams {
+					status := "enabled"
+					if !t.Enabled {
+						status = "disabled"
+					}
+
+					id := "-" // Disabled by a version that discarded IDs
+					if t.ID != 0 {
+						id = strconv.Itoa(t.ID)
+					}
+
+					row := []string{id, t.Name, status}
+					if wide {
+						usernames := make([]string, len(t.Members))
+						for i, m := range t.Members {
+							usernames[i] = m.Username
+						}
+						row = append(row, orDash(t.Host), orDash(strings.Join(usernames, ",")), t.Path)
+					}
+					table.Rows = append(table.Rows, row)
+				}
+
+				return table
+			})
 		},
 	}
+
+	addOutputFlag(cmd, &format)
+
+	return cmd
 }
 
 func teamCreateCmd() *cobra.Command {
@@ -116,7 +248,7 @@ func teamCreateCmd() *cobra.Command {
 		Use:   "create <id> <name>",
 		Short: "Create a new team",
 		Args:  cobra.ExactArgs(2),
-		RunE: func(cmd *cobra.Command, args []string) error {
+		RunE: audited("team.create", teamArg(1), func(cmd *cobra.Command, args []string) error {
 			id, err := strconv.Atoi(args[0])
 			if err != nil {
 				return fmt.Errorf("invalid team ID: %w", err)
@@ -125,42 +257,22 @@ func teamCreateCmd() *cobra.Command {
 			name := args[1]
 
 			mgr := team.New(cfg, log)
-			if err := mgr.Create(id, name, members); err != nil {
+			teamModel, err := mgr.Create(id, name, members)
+			if err != nil {
 				return err
 			}
 
 			// Generate compose file
-			challMgr := challenge.New(cfg, log)
-			challenges, err := challMgr.ListEnabled()
-			if err != nil {
-				return fmt.Errorf("failed to list challenges: %w", err)
-			}
-
-			composeGen := compose.New(cfg, log)
-
-			teamModel := model.Team{
-				ID:      id,
-				Name:    name,
-				Members: stringSliceToMembers(members),
-				Enabled: true,
-			}
-
-			composeYAML, err := composeGen.Generate(teamModel, challenges)
+			composePath, err := deploy.New(cfg, log).Regenerate(teamModel)
 			if err != nil {
 				return fmt.Errorf("failed to generate compose file: %w", err)
 			}
 
-			// Write compose file
-			composePath := cfg.GetTeamPath(model.FormatChallengeName(id, name, true)) + "/compose.yml"
-			if err := os.WriteFile(composePath, []byte(composeYAML), 0644); err != nil {
-				return fmt.Errorf("failed to write compose file: %w", err)
-			}
-
 			fmt.Printf("\n✓ Team '%s' created successfully (ID: %d)\n", name, id)
 			fmt.Printf("  Compose file: %s\n\n", composePath)
 
 			return nil
-		},
+		}),
 	}
 
 	cmd.Flags().StringSliceVarP(&members, "members", "m", []string{}, "Team members (comma-separated)")
@@ -169,41 +281,166 @@ func teamCreateCmd() *cobra.Command {
 }
 
 func teamDeleteCmd() *cobra.Command {
-	return &cobra.Command{
+	var (
+		yes  bool
+		opts team.DeleteOptions
+	)
+
+	cmd := &cobra.Command{
 		Use:   "delete <name>",
-		Short: "Delete a team",
+		Short: "Tear down a team's containers and delete it",
 		Args:  cobra.ExactArgs(1),
-		RunE: func(cmd *cobra.Command, args []string) error {
+		RunE: audited("team.delete", teamArg(0), func(cmd *cobra.Command, args []string) error {
+			if !yes {
+				question := fmt.Sprintf("Delete team '%s', its containers, networks and volumes", args[0])
+				if !opts.KeepKeys {
+					question += " and its VPN keys"
+				}
+				confirmed, err := confirm(question + "?")
+				if err != nil {
+					return err
+				}
+				if !confirmed {
+					return errors.New("deletion cancelled")
+				}
+			}
+
 			mgr := team.New(cfg, log)
-			if err := mgr.Delete(args[0]); err != nil {
+			keysArchive, err := mgr.Delete(cmd.Context(), args[0], opts)
+			if err != nil {
 				return err
 			}
 
-			fmt.Printf("\n✓ Team '%s' deleted successfully\n\n", args[0])
+			fmt.Printf("\n✓ Team '%s' deleted successfully\n", args[0])
+			if keysArchive != "" {
+				fmt.Printf("  VPN keys archived to %s\n", keysArchive)
+			}
+			fmt.Println()
 			return nil
-		},
+		}),
 	}
+
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Do not ask for confirmation")
+	cmd.Flags().BoolVar(&opts.KeepKeys, "keep-keys", false, "Archive the team's VPN keys and peer configs")
+
+	return cmd
 }
 
-func teamEnableCmd() *cobra.Command {
+func teamRenameCmd() *cobra.Command {
 	return &cobra.Command{
-		Use:   "enable <name> <id>",
-		Short: "Enable a disabled team",
+		Use:   "rename <name> <new-name>",
+		Short: "Rename a team and regenerate its stack",
 		Args:  cobra.ExactArgs(2),
-		RunE: func(cmd *cobra.Command, args []string) error {
+		RunE: audited("team.rename", renamedTeamArg(0, 1), func(cmd *cobra.Command, args []string) error {
+			result, err := team.New(cfg, log).Rename(cmd.Context(), args[0], args[1])
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("\n✓ Team '%s' renamed to '%s'\n", args[0], result.Team.Name)
+			printRelocation(result)
+			return nil
+		}),
+	}
+}
+
+func teamMoveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "move <name> <new-id>",
+		Short: "Give a team a new ID, re-addressing its network and VPN",
+		Args:  cobra.ExactArgs(2),
+		RunE: audited("team.move", teamArg(0), func(cmd *cobra.Command, args []string) error {
 			id, err := strconv.Atoi(args[1])
 			if err != nil {
 				return fmt.Errorf("invalid team ID: %w", err)
 			}
 
+			result, err := team.New(cfg, log).Move(cmd.Context(), args[0], id)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("\n✓ Team '%s' moved to ID %d (%s)\n", args[0], id, cfg.IPAM().Team(id).Subnet)
+			printRelocation(result)
+			return nil
+		}),
+	}
+}
+
+func teamPlaceCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "place [name] [host]",
+		Short: "Assign teams to the hosts of the inventory",
+		Long: `Assign every team that is not on an inventory host yet to the least loaded
+host, relative to host weights. With a team name, place only that team, on
+the given host if any. Team directories move under the directory of their host
+and VPN peer configurations point to the host address.`,
+		Args: cobra.MaximumNArgs(2),
+		RunE: audited("team.place", func(args []string) auditSubject {
+			if len(args) == 0 {
+				return auditSubject{}
+			}
+			return auditSubject{Team: args[0]}
+		}, func(cmd *cobra.Command, args []string) error {
 			mgr := team.New(cfg, log)
-			if err := mgr.Enable(args[0], id); err != nil {
+
+			var placements []team.Placement
+			if len(args) == 0 {
+				var err error
+				placements, err = mgr.Place(cmd.Context())
+				if err != nil {
+					return err
+				}
+			} else {
+				host := ""
+				if len(args) == 2 {
+					host = args[1]
+				}
+				placement, err := mgr.PlaceOn(cmd.Context(), args[0], host)
+				if err != nil {
+					return err
+				}
+				placements = append(placements, placement)
+			}
+
+			if len(placements) == 0 {
+				fmt.Printf("\n✓ Every team is already placed\n\n")
+				return nil
+			}
+			for _, p := range placements {
+				fmt.Printf("\n✓ Team '%s' placed on %s (VPN endpoint %s:%d)\n", p.Team.Name, p.Team.Host,
+					cfg.GetVPNAddress(p.Team.Host), cfg.GetVPNPort(p.Team.ID))
+				printRelocation(p.Relocation)
+			}
+			return nil
+		}),
+	}
+}
+
+func teamEnableCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "enable <name> [id]",
+		Short: "Enable a disabled team, with its previous ID by default",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: audited("team.enable", teamArg(0), func(cmd *cobra.Command, args []string) error {
+			id := 0
+			if len(args) == 2 {
+				var err error
+				if id, err = strconv.Atoi(args[1]); err != nil {
+					return fmt.Errorf("invalid team ID: %w", err)
+				}
+			}
+
+			mgr := team.New(cfg, log)
+			result, err := mgr.Enable(cmd.Context(), args[0], id)
+			if err != nil {
 				return err
 			}
 
-			fmt.Printf("\n✓ Team '%s' enabled successfully\n\n", args[0])
+			fmt.Printf("\n✓ Team '%s' enabled successfully (ID: %d)\n", args[0], result.Team.ID)
+			printRelocation(result)
 			return nil
-		},
+		}),
 	}
 }
 
@@ -212,7 +449,7 @@ func teamDisableCmd() *cobra.Command {
 		Use:   "disable <name>",
 		Short: "Disable a team",
 		Args:  cobra.ExactArgs(1),
-		RunE: func(cmd *cobra.Command, args []string) error {
+		RunE: audited("team.disable", teamArg(0), func(cmd *cobra.Command, args []string) error {
 			mgr := team.New(cfg, log)
 			if err := mgr.Disable(args[0]); err != nil {
 				return err
@@ -220,8 +457,51 @@ func teamDisableCmd() *cobra.Command {
 
 			fmt.Printf("\n✓ Team '%s' disabled successfully\n\n", args[0])
 			return nil
-		},
+		}),
+	}
+}
+
+func teamDeployCmd() *cobra.Command {
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "deploy <name|all>",
+		Short: "Regenerate and start a team stack",
+		Args:  cobra.ExactArgs(1),
+		RunE: audited("team.deploy", teamArg(0), func(cmd *cobra.Command, args []string) error {
+			teams, err := team.New(cfg, log).List()
+			if err != nil {
+				return err
+			}
+
+			var targets []model.Team
+			for _, t := range teams {
+				if t.Enabled && (args[0] == "all" || t.Name == args[0]) {
+					targets = append(targets, t)
+				}
+			}
+			if len(targets) == 0 {
+				return fmt.Errorf("enabled team %s not found", args[0])
+			}
+
+			deployer := deploy.New(cfg, log)
+			for _, t := range targets {
+				if err := deployer.Deploy(cmd.Context(), t, force); err != nil {
+					if errors.Is(err, deploy.ErrOutsideEvent) {
+						return fmt.Errorf("%w (use --force to deploy anyway)", err)
+					}
+					return fmt.Errorf("failed to deploy team %s: %w", t.Name, err)
+				}
+				fmt.Printf("✓ Team '%s' deployed\n", t.Name)
+			}
+
+			return nil
+		}),
 	}
+
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Deploy even outside the event window")
+
+	return cmd
 }
 
 // challengeCmd returns the challenge management command
@@ -232,56 +512,142 @@ func challengeCmd() *cobra.Command {
 	}
 
 	cmd.AddCommand(challengeListCmd())
+	cmd.AddCommand(challengeAddCmd())
 	cmd.AddCommand(challengeValidateCmd())
 	cmd.AddCommand(challengeEnableCmd())
 	cmd.AddCommand(challengeDisableCmd())
+	cmd.AddCommand(challengeResetCmd())
 
 	return cmd
 }
 
 func challengeListCmd() *cobra.Command {
-	var showDisabled bool
+	var (
+		showDisabled bool
+		format       string
+	)
 
 	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List all challenges",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			outputFormat, err := output.ParseFormat(format)
+			if err != nil {
+				return err
+			}
+
 			mgr := challenge.New(cfg, log)
-			challenges, err := mgr.List()
+			all, err := mgr.List()
+			if err != nil {
+				return err
+			}
+
+			unregistered, err := mgr.Unregistered()
 			if err != nil {
 				return err
 			}
+			for _, dir := range unregistered {
+				log.Warn("Unregistered challenge directory (use 'challenge add' or 'migrate')", "dir", dir)
+			}
+
+			challenges := make([]model.Challenge, 0, len(all))
+			for _, ch := range all {
+				if showDisabled || ch.Enabled {
+					challenges = append(challenges, ch)
+				}
+			}
 
-			if len(challenges) == 0 {
+			if len(challenges) == 0 && !outputFormat.Structured() {
 				log.Info("No challenges found")
 				return nil
 			}
 
-			fmt.Println("\nChallenges:")
-			for _, ch := range challenges {
-				if !showDisabled && !ch.Enabled {
-					continue
+			now := time.Now()
+			return printOutput(outputFormat, challenges, func(wide bool) output.Table {
+				table := output.Table{Headers: []string{"NETWORK ID", "NAME", "STATUS"}}
+				if wide {
+					table.Headers = append(table.Headers, "RELEASE", "HEALTHCHECK", "RESET", "PATH")
 				}
 
-				status := "enabled"
-				if !ch.Enabled {
-					status = "disabled"
-				}
+				for _, ch := range challenges {
+					status := "enabled"
+					if !ch.Enabled {
+						status = "disabled"
+					}
+
+					networkID := "N/A"
+					if ch.Enabled {
+						networkID = strconv.Itoa(ch.NetworkID)
+					}
 
-				networkID := "N/A"
-				if ch.Enabled {
-					networkID = fmt.Sprintf("%d", ch.NetworkID)
+					row := []string{networkID, ch.Name, status}
+					if wide {
+						release := "-"
+						if ch.Release != nil {
+							release = formatEventTime(*ch.Release)
+							if !ch.Released(now) {
+								release += " (pending)"
+							}
+						}
+						healthcheck := "-"
+						if ch.Healthcheck != nil {
+							healthcheck = ch.Healthcheck.Type
+						}
+						resetSchedule := "-"
+						if ch.Reset != nil {
+							resetSchedule = ch.Reset.String()
+						}
+						row = append(row, release, healthcheck, resetSchedule, ch.BuildPath)
+					}
+					table.Rows = append(table.Rows, row)
 				}
 
-				fmt.Printf("  [%s] %s (%s)\n", networkID, ch.Name, status)
+				return table
+			})
+		},
+	}
+
+	cmd.Flags().BoolVarP(&showDisabled, "all", "a", false, "Show disabled challenges")
+	addOutputFlag(cmd, &format)
+
+	return cmd
+}
+
+func challengeAddCmd() *cobra.Command {
+	var (
+		name      string
+		networkID int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "add <directory>",
+		Short: "Register a challenge directory",
+		Args:  cobra.ExactArgs(1),
+		RunE: audited("challenge.add", func(args []string) auditSubject {
+			if name != "" {
+				return auditSubject{Challenge: name}
+			}
+			return auditSubject{Challenge: args[0]}
+		}, func(cmd *cobra.Command, args []string) error {
+			mgr := challenge.New(cfg, log)
+			if err := mgr.Add(args[0], name, networkID); err != nil {
+				return err
 			}
-			fmt.Println()
 
+			if name == "" {
+				name = args[0]
+			}
+			if networkID == 0 {
+				fmt.Printf("\n✓ Challenge '%s' added (disabled)\n\n", name)
+			} else {
+				fmt.Printf("\n✓ Challenge '%s' added with network ID %d\n\n", name, networkID)
+			}
 			return nil
-		},
+		}),
 	}
 
-	cmd.Flags().BoolVarP(&showDisabled, "all", "a", false, "Show disabled challenges")
+	cmd.Flags().StringVarP(&name, "name", "n", "", "Challenge name (defaults to the directory name)")
+	cmd.Flags().IntVarP(&networkID, "network-id", "i", 0, "Network ID to enable the challenge with")
 
 	return cmd
 }
@@ -296,7 +662,7 @@ func challengeValidateCmd() *cobra.Command {
 				return err
 			}
 
-			fmt.Println("\n✓ All challenges are valid\n")
+			fmt.Print("\n✓ All challenges are valid\n\n")
 			return nil
 		},
 	}
@@ -307,7 +673,7 @@ func challengeEnableCmd() *cobra.Command {
 		Use:   "enable <name> <network-id>",
 		Short: "Enable a disabled challenge",
 		Args:  cobra.ExactArgs(2),
-		RunE: func(cmd *cobra.Command, args []string) error {
+		RunE: audited("challenge.enable", challengeArg(0), func(cmd *cobra.Command, args []string) error {
 			networkID, err := strconv.Atoi(args[1])
 			if err != nil {
 				return fmt.Errorf("invalid network ID: %w", err)
@@ -320,7 +686,7 @@ func challengeEnableCmd() *cobra.Command {
 
 			fmt.Printf("\n✓ Challenge '%s' enabled with network ID %d\n\n", args[0], networkID)
 			return nil
-		},
+		}),
 	}
 }
 
@@ -329,7 +695,7 @@ func challengeDisableCmd() *cobra.Command {
 		Use:   "disable <name>",
 		Short: "Disable a challenge",
 		Args:  cobra.ExactArgs(1),
-		RunE: func(cmd *cobra.Command, args []string) error {
+		RunE: audited("challenge.disable", challengeArg(0), func(cmd *cobra.Command, args []string) error {
 			mgr := challenge.New(cfg, log)
 			if err := mgr.Disable(args[0]); err != nil {
 				return err
@@ -337,15 +703,583 @@ func challengeDisableCmd() *cobra.Command {
 
 			fmt.Printf("\n✓ Challenge '%s' disabled successfully\n\n", args[0])
 			return nil
+		}),
+	}
+}
+
+func challengeResetCmd() *cobra.Command {
+	var (
+		teamName string
+		force    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "reset <name>",
+		Short: "Recreate a challenge container from a clean image",
+		Args:  cobra.ExactArgs(1),
+		RunE: audited("challenge.reset", func(args []string) auditSubject {
+			if teamName == "all" {
+				return auditSubject{Challenge: args[0]}
+			}
+			return auditSubject{Team: teamName, Challenge: args[0]}
+		}, func(cmd *cobra.Command, args []string) error {
+			// Challenges not released yet have no service in team compose files
+			challenges, err := challenge.New(cfg, log).ListReleased(time.Now())
+			if err != nil {
+				return err
+			}
+
+			var found *model.Challenge
+			for _, ch := range challenges {
+				if ch.Name == args[0] {
+					found = &ch
+					break
+				}
+			}
+			if found == nil {
+				return fmt.Errorf("released challenge %s not found", args[0])
+			}
+
+			teams, err := team.New(cfg, log).List()
+			if err != nil {
+				return err
+			}
+
+			var targets []model.Team
+			for _, t := range teams {
+				if t.Enabled && (teamName == "all" || t.Name == teamName) {
+					targets = append(targets, t)
+				}
+			}
+			if len(targets) == 0 {
+				return fmt.Errorf("enabled team %s not found", teamName)
+			}
+
+			resetter := reset.New(cfg, log)
+			var failed []string
+			for _, t := range targets {
+				if err := resetter.Reset(cmd.Context(), t, *found, force); err != nil {
+					log.Error("Reset failed", "team", t.Name, "challenge", found.Name, "error", err)
+					failed = append(failed, t.Name)
+					continue
+				}
+				fmt.Printf("✓ Challenge '%s' reset for team '%s'\n", found.Name, t.Name)
+			}
+
+			if len(failed) > 0 {
+				return fmt.Errorf("%d of %d resets failed: %s", len(failed), len(targets), strings.Join(failed, ", "))
+			}
+			return nil
+		}),
+	}
+
+	cmd.Flags().StringVarP(&teamName, "team", "t", "", "Team to reset the challenge for (name or 'all')")
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Ignore the reset rate limit")
+	cmd.MarkFlagRequired("team")
+
+	return cmd
+}
+
+// checkResult is the outcome of a healthcheck shown by the check command
+type checkResult struct {
+	Team      string `json:"team"`
+	Challenge string `json:"challenge"`
+	Address   string `json:"address"`
+	Healthy   bool   `json:"healthy"`
+	Error     string `json:"error,omitempty"`
+}
+
+// checkCmd probes every team's challenge instances
+func checkCmd() *cobra.Command {
+	var (
+		teamName string
+		format   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Probe challenge healthchecks for every team",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			outputFormat, err := output.ParseFormat(format)
+			if err != nil {
+				return err
+			}
+
+			teams, err := team.New(cfg, log).List()
+			if err != nil {
+				return err
+			}
+
+			var targets []model.Team
+			for _, t := range teams {
+				if t.Enabled && (teamName == "" || t.Name == teamName) {
+					targets = append(targets, t)
+				}
+			}
+			if len(targets) == 0 {
+				return fmt.Errorf("no enabled team to check")
+			}
+
+			challenges, err := challenge.New(cfg, log).ListReleased(time.Now())
+			if err != nil {
+				return err
+			}
+
+			var probed []model.Challenge
+			for _, ch := range challenges {
+				// On-demand instances are not running most of the time
+				if ch.Healthcheck != nil && !ch.OnDemand {
+					probed = append(probed, ch)
+				}
+			}
+			if len(probed) == 0 {
+				log.Info("No challenge declares a healthcheck")
+				return nil
+			}
+
+			results := health.New(cfg, log).CheckAll(cmd.Context(), targets, probed)
+
+			status := make(map[string]map[string]health.Result)
+			failures := 0
+			for _, r := range results {
+				if status[r.Team] == nil {
+					status[r.Team] = make(map[string]health.Result)
+				}
+				status[r.Team][r.Challenge] = r
+				if !r.Healthy() {
+					failures++
+				}
+			}
+
+			if outputFormat.Structured() {
+				report := make([]checkResult, 0, len(results))
+				for _, t := range targets {
+					for _, ch := range probed {
+						r := status[t.Name][ch.Name]
+						entry := checkResult{Team: r.Team, Challenge: r.Challenge, Address: r.Address, Healthy: r.Healthy()}
+						if r.Err != nil {
+							entry.Error = r.Err.Error()
+						}
+						report = append(report, entry)
+					}
+				}
+				if err := printOutput(outputFormat, report, nil); err != nil {
+					return err
+				}
+				if failures > 0 {
+					return fmt.Errorf("%d of %d healthchecks failed", failures, len(results))
+				}
+				return nil
+			}
+
+			err = printOutput(outputFormat, results, func(wide bool) output.Table {
+				table := output.Table{Headers: []string{"TEAM"}}
+				for _, ch := range probed {
+					table.Headers = append(table.Headers, ch.Name)
+				}
+				for _, t := range targets {
+					row := []string{t.Name}
+					for _, ch := range probed {
+						cell := "OK"
+						if r := status[t.Name][ch.Name]; !r.Healthy() {
+							cell = "FAIL"
+						}
+						row = append(row, cell)
+					}
+					table.Rows = append(table.Rows, row)
+				}
+				return table
+			})
+			if err != nil {
+				return err
+			}
+
+			if failures > 0 {
+				for _, t := range targets {
+					for _, ch := range probed {
+						if r := status[t.Name][ch.Name]; !r.Healthy() {
+							fmt.Printf("  ✗ %s/%s (%s): %v\n", t.Name, ch.Name, r.Address, r.Err)
+						}
+					}
+				}
+				fmt.Println()
+				return fmt.Errorf("%d of %d healthchecks failed", failures, len(results))
+			}
+
+			fmt.Printf("✓ All %d healthchecks passed\n\n", len(results))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&teamName, "team", "t", "", "Only check the given team")
+	addOutputFlag(cmd, &format)
+
+	return cmd
+}
+
+// undoCmd reverts the last journaled operations
+func undoCmd() *cobra.Command {
+	var (
+		list   bool
+		format string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "undo [count]",
+		Short: "Undo the last mutating operations",
+		Long: `Undo the last mutating operations (1 by default), restoring the previous state
+and the team directories they removed. Use --list to show the operations that can be undone.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: audited("undo", nil, func(cmd *cobra.Command, args []string) error {
+			st := store.FromConfig(cfg)
+
+			if list {
+				outputFormat, err := output.ParseFormat(format)
+				if err != nil {
+					return err
+				}
+
+				ops, err := st.History()
+				if err != nil {
+					return err
+				}
+				if len(ops) == 0 && !outputFormat.Structured() {
+					log.Info("Nothing to undo")
+					return nil
+				}
+
+				return printOutput(outputFormat, ops, func(wide bool) output.Table {
+					table := output.Table{Headers: []string{"#", "TIME", "USER", "ACTION"}}
+					if wide {
+						table.Headers = append(table.Headers, "COMMAND")
+					}
+					// Number operations as undo counts: 1 is the last one
+					for i := len(ops) - 1; i >= 0; i-- {
+						op := ops[i]
+						row := []string{strconv.Itoa(len(ops) - i), op.Time.Local().Format(time.DateTime), op.User, op.Action}
+						if wide {
+							row = append(row, op.Command)
+						}
+						table.Rows = append(table.Rows, row)
+					}
+					return table
+				})
+			}
+
+			count := 1
+			if len(args) == 1 {
+				n, err := strconv.Atoi(args[0])
+				if err != nil || n < 1 {
+					return fmt.Errorf("invalid count %q", args[0])
+				}
+				count = n
+			}
+
+			undone, err := st.Undo(count)
+			for i := len(undone) - 1; i >= 0; i-- {
+				fmt.Printf("✓ Undone %s (%s)\n", undone[i].Action, undone[i].Time.Local().Format(time.DateTime))
+			}
+			if err != nil {
+				return err
+			}
+
+			fmt.Println("\nRegenerate or redeploy affected team stacks with 'team deploy' if needed")
+			return nil
+		}),
+	}
+
+	cmd.Flags().BoolVarP(&list, "list", "l", false, "List the operations that can be undone")
+	addOutputFlag(cmd, &format)
+
+	return cmd
+}
+
+// backupCmd archives the event state
+func backupCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "backup <archive>",
+		Short: "Archive teams, VPN keys, state and configuration to a tar.gz file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			manifest, err := backup.New(cfg, log).Backup(args[0], configPath)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("\n✓ Backup written to %s (%d teams, %d challenges)\n\n",
+				args[0], manifest.Teams, manifest.Challenges)
+			return nil
+		},
+	}
+}
+
+// restoreCmd restores the event state from a backup archive
+func restoreCmd() *cobra.Command {
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "restore <archive>",
+		Short: "Restore teams, VPN keys, state and configuration from a backup archive",
+		Args:  cobra.ExactArgs(1),
+		RunE: audited("restore", nil, func(cmd *cobra.Command, args []string) error {
+			manifest, err := backup.New(cfg, log).Restore(args[0], configPath, force)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("\n✓ Backup from %s restored (%d teams, %d challenges)\n",
+				manifest.CreatedAt.Local().Format(time.DateTime), manifest.Teams, manifest.Challenges)
+			if manifest.HasConfig {
+				fmt.Printf("  Configuration restored to %s, used from the next command on\n", configPath)
+
+				// Teams and data were restored to the paths of the configuration in use
+				restored, err := config.Load(configPath)
+				switch {
+				case err != nil:
+					log.Warn("Restored configuration is invalid", "error", err)
+				case restored.Paths.Teams != cfg.Paths.Teams || restored.Paths.Data != cfg.Paths.Data:
+					log.Warn("Restored configuration uses other paths than the restored teams and data",
+						"teams", restored.Paths.Teams, "data", restored.Paths.Data)
+				}
+			}
+			fmt.Println()
+			return nil
+		}),
+	}
+
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Overwrite existing teams, state and configuration")
+
+	return cmd
+}
+
+// eventStatus is the event timeline shown by the event command
+type eventStatus struct {
+	Phase    string          `json:"phase"`
+	Start    *time.Time      `json:"start"`
+	Freeze   *time.Time      `json:"freeze"`
+	End      *time.Time      `json:"end"`
+	Releases []releaseStatus `json:"releases"`
+}
+
+type releaseStatus struct {
+	Challenge string     `json:"challenge"`
+	NetworkID int        `json:"network_id"`
+	Release   *time.Time `json:"release"`
+	Released  bool       `json:"released"`
+}
+
+// eventCmd shows the event timeline
+func eventCmd() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "event",
+		Short: "Show the event timeline and challenge releases",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			outputFormat, err := output.ParseFormat(format)
+			if err != nil {
+				return err
+			}
+
+			now := time.Now()
+			ev := cfg.Event
+
+			status := eventStatus{
+				Phase:    "running",
+				Start:    optionalTime(ev.Start),
+				Freeze:   optionalTime(ev.Freeze),
+				End:      optionalTime(ev.End),
+				Releases: []releaseStatus{},
+			}
+			switch {
+			case !ev.Start.IsZero() && now.Before(ev.Start):
+				status.Phase = "not started"
+			case !ev.Running(now):
+				status.Phase = "ended"
+			case ev.Frozen(now):
+				status.Phase = "running (frozen)"
+			}
+
+			challenges, err := challenge.New(cfg, log).ListEnabled()
+			if err != nil {
+				return err
+			}
+			for _, ch := range challenges {
+				status.Releases = append(status.Releases, releaseStatus{
+					Challenge: ch.Name,
+					NetworkID: ch.NetworkID,
+					Release:   ch.Release,
+					Released:  ch.Released(now),
+				})
+			}
+
+			if outputFormat.Structured() {
+				return printOutput(outputFormat, status, nil)
+			}
+
+			fmt.Printf("\nEvent: %s\n", status.Phase)
+			fmt.Printf("  Start:  %s\n", formatEventTime(ev.Start))
+			fmt.Printf("  Freeze: %s\n", formatEventTime(ev.Freeze))
+			fmt.Printf("  End:    %s\n", formatEventTime(ev.End))
+
+			return printOutput(outputFormat, status, func(wide bool) output.Table {
+				table := output.Table{Headers: []string{"NETWORK ID", "CHALLENGE", "RELEASE", "STATUS"}}
+				for _, r := range status.Releases {
+					release := "immediate"
+					if r.Release != nil {
+						release = formatEventTime(*r.Release)
+					}
+					state := "released"
+					if !r.Released {
+						state = "pending"
+					}
+					table.Rows = append(table.Rows, []string{strconv.Itoa(r.NetworkID), r.Challenge, release, state})
+				}
+				return table
+			})
+		},
+	}
+
+	addOutputFlag(cmd, &format)
+
+	return cmd
+}
+
+// daemonCmd runs the long-running background services
+func daemonCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "daemon",
+		Short: "Run scheduled resets, the event timeline, game mode pollers and HTTP endpoints",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := cfg.Validate(); err != nil {
+				return fmt.Errorf("configuration validation failed: %w", err)
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			services := map[string]func(context.Context) error{
+				"scheduler": scheduler.New(cfg, log).Run,
+				"timeline":  event.New(cfg, log).Run,
+			}
+			switch cfg.Mode {
+			case config.ModeAttackDefense:
+				services["checker"] = ad.New(cfg, log).Run
+			case config.ModeKingOfTheHill:
+				services["koth"] = koth.New(cfg, log).Run
+			}
+			if cfg.Instancer.Listen != "" {
+				services["instancer"] = instancer.New(cfg, log).Run
+			}
+			if cfg.Metrics.Listen != "" {
+				services["metrics"] = metrics.New(cfg, log).Run
+			}
+
+			log.Info("Daemon started")
+			errs := make(chan error, len(services))
+			for name, run := range services {
+				go func() {
+					if err := run(ctx); err != nil {
+						errs <- fmt.Errorf("%s: %w", name, err)
+						return
+					}
+					errs <- nil
+				}()
+			}
+
+			// Stop every service as soon as one of them fails
+			var firstErr error
+			for range services {
+				if err := <-errs; err != nil && firstErr == nil {
+					firstErr = err
+					stop()
+				}
+			}
+			if firstErr != nil {
+				return firstErr
+			}
+
+			log.Info("Daemon stopped")
+			return nil
 		},
 	}
 }
 
 // Helper functions
-func stringSliceToMembers(names []string) []model.Member {
-	members := make([]model.Member, len(names))
-	for i, name := range names {
-		members[i] = model.Member{Username: name}
+
+// printRelocation reports what a team rename or move changed for its players
+func printRelocation(result team.Relocation) {
+	if result.Redeployed {
+		fmt.Println("  Stack redeployed; challenge containers start from a clean state")
+	}
+	if result.VPNChanged > 0 {
+		fmt.Printf("  ⚠ %d VPN peer configs were re-addressed: clients must download their new config from %s\n",
+			result.VPNChanged, filepath.Join(result.Team.Path, "config", "peer*"))
+	}
+	fmt.Println()
+}
+
+// confirm asks a yes/no question on the terminal
+func confirm(question string) (bool, error) {
+	if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false, errors.New("confirmation required: run interactively or pass --yes")
+	}
+
+	fmt.Printf("%s [y/N] ", question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false, err
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+// optionalTime returns nil for the zero time, so unset times are left out of structured output
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// addOutputFlag registers the -o/--output flag of read commands
+func addOutputFlag(cmd *cobra.Command, format *string) {
+	cmd.Flags().StringVarP(format, "output", "o", string(output.FormatTable), "Output format (table, wide, json, yaml)")
+}
+
+// printOutput writes a command result to stdout, framing tables with blank lines
+func printOutput(format output.Format, data any, table output.TableFunc) error {
+	if format.Structured() {
+		return output.Print(os.Stdout, format, data, table)
+	}
+
+	fmt.Println()
+	if err := output.Print(os.Stdout, format, data, table); err != nil {
+		return err
+	}
+	fmt.Println()
+	return nil
+}
+
+// printMigrationReport lists the teams and challenges imported by a migration
+func printMigrationReport(report migrate.Report) {
+	fmt.Printf("\nImported %d team(s) and %d challenge(s)\n", len(report.Teams), len(report.Challenges))
+	for _, t := range report.Teams {
+		fmt.Printf("  team      [%d] %s (%s)\n", t.ID, t.Name, t.Dir)
+	}
+	for _, ch := range report.Challenges {
+		fmt.Printf("  challenge [%d] %s (%s)\n", ch.NetworkID, ch.Name, ch.Dir)
+	}
+	for _, skipped := range report.Skipped {
+		fmt.Printf("  skipped   %s\n", skipped)
+	}
+	fmt.Println()
+}
+
+func formatEventTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
 	}
-	return members
+	return t.Local().Format(time.DateTime)
 }
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/Lolozendev/CTFManager/internal/app/deploy"
+	"github.com/Lolozendev/CTFManager/internal/app/team"
+	"github.com/Lolozendev/CTFManager/internal/output"
+	"github.com/spf13/cobra"
+)
+
+func networkCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "network",
+		Short: "Inspect the address plan of team and game networks",
+	}
+
+	cmd.AddCommand(networkCheckCmd())
+
+	return cmd
+}
+
+func networkCheckCmd() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Look for host routes and Docker networks overlapping the managed networks",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			outputFormat, err := output.ParseFormat(format)
+			if err != nil {
+				return err
+			}
+
+			teams, err := team.New(cfg, log).List()
+			if err != nil {
+				return err
+			}
+
+			conflicts, err := deploy.New(cfg, log).Conflicts(cmd.Context(), teams)
+			if err != nil {
+				return err
+			}
+
+			if len(conflicts) == 0 && !outputFormat.Structured() {
+				log.Info("No overlapping network", "teams", cfg.IPAM().Parent())
+				return nil
+			}
+
+			if err := printOutput(outputFormat, conflicts, func(wide bool) output.Table {
+				table := output.Table{Headers: []string{"NETWORK", "SUBNET", "SOURCE", "NAME", "OVERLAP"}}
+				for _, c := range conflicts {
+					table.Rows = append(table.Rows, []string{c.Network, c.Subnet, c.Source, c.Name, c.Overlap})
+				}
+				return table
+			}); err != nil {
+				return err
+			}
+
+			if len(conflicts) > 0 {
+				return fmt.Errorf("%d overlapping networks", len(conflicts))
+			}
+			return nil
+		},
+	}
+
+	addOutputFlag(cmd, &format)
+
+	return cmd
+}
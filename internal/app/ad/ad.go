@@ -0,0 +1,192 @@
+// Package ad runs the service checks of attack-defense events
+package ad
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Lolozendev/CTFManager/internal/app/team"
+	"github.com/Lolozendev/CTFManager/internal/checker"
+	"github.com/Lolozendev/CTFManager/internal/config"
+	"github.com/charmbracelet/log"
+)
+
+// maxConcurrentChecks bounds the number of services checked at the same time
+const maxConcurrentChecks = 32
+
+// Runner checks every service of every team each tick and records their SLA
+type Runner struct {
+	config   *config.Config
+	logger   *log.Logger
+	teams    *team.Manager
+	checkers map[string]checker.Checker
+}
+
+// New creates a new checker runner using the checker scripts of the configuration
+func New(cfg *config.Config, logger *log.Logger) *Runner {
+	checkers := make(map[string]checker.Checker)
+	for _, svc := range cfg.AttackDefense.Services {
+		checkers[svc.Name] = checker.Exec{Path: svc.Checker}
+	}
+
+	return &Runner{
+		config:   cfg,
+		logger:   logger,
+		teams:    team.New(cfg, logger),
+		checkers: checkers,
+	}
+}
+
+// Use replaces the checker of a service, e.g. with one written in Go
+func (r *Runner) Use(service string, c checker.Checker) {
+	r.checkers[service] = c
+}
+
+// Run checks services every tick while the event is running, until the context is cancelled
+func (r *Runner) Run(ctx context.Context) error {
+	if r.config.AttackDefense.Tick <= 0 {
+		return fmt.Errorf("invalid attack-defense tick %s", r.config.AttackDefense.Tick)
+	}
+	ticker := time.NewTicker(r.config.AttackDefense.Tick)
+	defer ticker.Stop()
+
+	r.logger.Info("Service checker started", "tick", r.config.AttackDefense.Tick, "services", len(r.checkers))
+	for {
+		if r.config.Event.Running(time.Now()) {
+			if err := r.Tick(ctx); err != nil {
+				r.logger.Error("Tick failed", "error", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			r.logger.Info("Service checker stopped")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick checks every service of every enabled team once and saves their status
+func (r *Runner) Tick(ctx context.Context) error {
+	sla, err := LoadSLA(r.slaPath())
+	if err != nil {
+		return err
+	}
+
+	teams, err := r.teams.List()
+	if err != nil {
+		return err
+	}
+
+	sla.Tick++
+	game := r.config.GameNetwork()
+
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, maxConcurrentChecks)
+	)
+
+	for _, t := range teams {
+		if !t.Enabled {
+			continue
+		}
+
+		for _, svc := range r.config.AttackDefense.Services {
+			target := checker.Target{
+				Team:    t.Name,
+				Service: svc.Name,
+				Address: game.VulnboxIP(t.ID),
+				Port:    svc.Port,
+			}
+
+			mu.Lock()
+			status := sla.Service(t.Name, svc.Name)
+			previous := *status
+			mu.Unlock()
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+
+				current := r.check(ctx, r.checkers[svc.Name], target, previous, sla.Tick)
+
+				mu.Lock()
+				*status = current
+				mu.Unlock()
+			}()
+		}
+	}
+
+	wg.Wait()
+
+	r.logger.Info("Tick completed", "tick", sla.Tick)
+	return saveSLA(r.slaPath(), sla)
+}
+
+// check runs one round of a checker against a service: check, plant a new
+// flag, then retrieve the oldest flag still alive
+func (r *Runner) check(ctx context.Context, c checker.Checker, target checker.Target, status ServiceStatus, tick int) ServiceStatus {
+	ctx, cancel := context.WithTimeout(ctx, r.config.AttackDefense.CheckerTimeout)
+	defer cancel()
+
+	flag := newFlag(tick)
+	err := c.Check(ctx, target)
+	if err == nil {
+		err = c.PutFlag(ctx, target, flag)
+	}
+	if err == nil {
+		status.Flags = append(status.Flags, flag)
+	}
+
+	// Forget flags past their lifetime
+	for len(status.Flags) > 0 && status.Flags[0].Tick <= tick-r.config.AttackDefense.FlagLifetime {
+		status.Flags = status.Flags[1:]
+	}
+	if err == nil && len(status.Flags) > 1 {
+		err = c.GetFlag(ctx, target, status.Flags[0])
+	}
+
+	status.Status, status.Message = checker.StatusOf(err)
+	status.Checked = time.Now()
+	status.Ticks++
+	if status.Status == checker.StatusOK {
+		status.Up++
+	} else {
+		r.logger.Warn("Service check failed", "team", target.Team, "service", target.Service,
+			"status", status.Status, "message", status.Message)
+	}
+
+	return status
+}
+
+// Status returns the last recorded status of every service
+func (r *Runner) Status() (*SLA, error) {
+	return LoadSLA(r.slaPath())
+}
+
+func (r *Runner) slaPath() string {
+	return r.config.GetDataPath(SLAFileName)
+}
+
+// newFlag generates a random flag for a tick
+func newFlag(tick int) checker.Flag {
+	return checker.Flag{
+		ID:    randomHex(8),
+		Value: "FLAG{" + randomHex(16) + "}",
+		Tick:  tick,
+	}
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
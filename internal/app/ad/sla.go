@@ -0,0 +1,94 @@
+package ad
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Lolozendev/CTFManager/internal/checker"
+)
+
+// SLAFileName is the name of the service status file in the data directory
+const SLAFileName = "sla.json"
+
+// ServiceStatus is the state of a service of a team over the event
+type ServiceStatus struct {
+	Status  checker.Status `json:"status" yaml:"status"`
+	Message string         `json:"message,omitempty" yaml:"message,omitempty"`
+	Checked time.Time      `json:"checked" yaml:"checked"`
+	Ticks   int            `json:"ticks" yaml:"ticks"`                     // Ticks the service was checked at
+	Up      int            `json:"up" yaml:"up"`                           // Ticks the service was OK at
+	Flags   []checker.Flag `json:"flags,omitempty" yaml:"flags,omitempty"` // Flags still alive, oldest first
+}
+
+// SLA returns the ratio of ticks the service was OK at
+func (s ServiceStatus) SLA() float64 {
+	if s.Ticks == 0 {
+		return 0
+	}
+	return float64(s.Up) / float64(s.Ticks)
+}
+
+// SLA is the status of every service of every team
+type SLA struct {
+	Tick  int                                  `json:"tick"`
+	Teams map[string]map[string]*ServiceStatus `json:"teams"`
+}
+
+// Service returns the status of a service of a team, creating it if needed
+func (s *SLA) Service(team string, service string) *ServiceStatus {
+	if s.Teams == nil {
+		s.Teams = make(map[string]map[string]*ServiceStatus)
+	}
+	if s.Teams[team] == nil {
+		s.Teams[team] = make(map[string]*ServiceStatus)
+	}
+	if s.Teams[team][service] == nil {
+		s.Teams[team][service] = &ServiceStatus{}
+	}
+	return s.Teams[team][service]
+}
+
+// LoadSLA reads the service status file, returning an empty SLA if it does not exist
+func LoadSLA(path string) (*SLA, error) {
+	sla := &SLA{Teams: make(map[string]map[string]*ServiceStatus)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return sla, nil
+		}
+		return nil, fmt.Errorf("failed to read service status: %w", err)
+	}
+
+	if err := json.Unmarshal(data, sla); err != nil {
+		return nil, fmt.Errorf("invalid service status: %w", err)
+	}
+
+	return sla, nil
+}
+
+// saveSLA atomically writes the service status file, which holds planted flags
+func saveSLA(path string, sla *SLA) error {
+	data, err := json.MarshalIndent(sla, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write service status: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write service status: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,536 @@
+// Package backup archives and restores the full event state
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Lolozendev/CTFManager/internal/config"
+	"github.com/Lolozendev/CTFManager/internal/lock"
+	"github.com/Lolozendev/CTFManager/internal/store"
+	"github.com/charmbracelet/log"
+)
+
+// FormatVersion is the archive format version written by Backup
+const FormatVersion = 1
+
+// Archive layout
+const (
+	manifestName = "manifest.json"
+	teamsPrefix  = "teams"
+	dataPrefix   = "data"
+	configName   = "config/config.yml"
+)
+
+// Manifest describes a backup archive
+type Manifest struct {
+	FormatVersion int       `json:"format_version"`
+	CreatedAt     time.Time `json:"created_at"`
+	Host          string    `json:"host"`
+	Teams         int       `json:"teams"`
+	Challenges    int       `json:"challenges"`
+	HasConfig     bool      `json:"has_config"`
+}
+
+// Manager handles backup and restore operations
+type Manager struct {
+	config *config.Config
+	logger *log.Logger
+	store  *store.Store
+}
+
+// New creates a new backup manager
+func New(cfg *config.Config, logger *log.Logger) *Manager {
+	return &Manager{
+		config: cfg,
+		logger: logger,
+		store:  store.FromConfig(cfg),
+	}
+}
+
+// Backup writes the team directories (including WireGuard keys and peer
+// configs), the data directory and the configuration file to a tar.gz archive
+func (m *Manager) Backup(archivePath string, configPath string) (Manifest, error) {
+	l, err := m.store.Lock()
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer l.Release()
+
+	state, err := m.store.Load()
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	host, _ := os.Hostname()
+	manifest := Manifest{
+		FormatVersion: FormatVersion,
+		CreatedAt:     time.Now().UTC(),
+		Host:          host,
+		Teams:         len(state.Teams),
+		Challenges:    len(state.Challenges),
+	}
+	if _, err := os.Stat(configPath); err == nil {
+		manifest.HasConfig = true
+	}
+
+	// Write to a temporary file so that a failed backup never leaves a truncated archive
+	tmp := archivePath + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return manifest, fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer os.Remove(tmp)
+
+	if err := m.writeArchive(f, manifest, configPath); err != nil {
+		f.Close()
+		return manifest, err
+	}
+	if err := f.Close(); err != nil {
+		return manifest, fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	if err := os.Rename(tmp, archivePath); err != nil {
+		return manifest, fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	m.logger.Info("Backup created", "archive", archivePath, "teams", manifest.Teams, "challenges", manifest.Challenges)
+	return manifest, nil
+}
+
+func (m *Manager) writeArchive(w io.Writer, manifest Manifest, configPath string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := writeFile(tw, manifestName, data, 0644); err != nil {
+		return err
+	}
+
+	if err := addTree(tw, m.config.Paths.Teams, teamsPrefix, nil); err != nil {
+		return fmt.Errorf("failed to archive teams: %w", err)
+	}
+
+	// The lock file only matters to running processes
+	skipLock := func(rel string) bool {
+		return rel == lock.FileName || strings.HasSuffix(rel, ".tmp")
+	}
+	if err := addTree(tw, m.config.Paths.Data, dataPrefix, skipLock); err != nil {
+		return fmt.Errorf("failed to archive data directory: %w", err)
+	}
+
+	if manifest.HasConfig {
+		data, err := os.ReadFile(configPath)
+		if err != nil {
+			return fmt.Errorf("failed to read configuration: %w", err)
+		}
+		if err := writeFile(tw, configName, data, 0644); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return nil
+}
+
+// ArchiveDir writes the content of a directory to a tar.gz file
+func ArchiveDir(src string, archivePath string) error {
+	if err := os.MkdirAll(filepath.Dir(archivePath), 0700); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	f, err := os.OpenFile(archivePath, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	err = addTree(tw, src, filepath.Base(src), nil)
+	if err == nil {
+		err = tw.Close()
+	}
+	if err == nil {
+		err = gz.Close()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(archivePath)
+		return fmt.Errorf("failed to archive %s: %w", src, err)
+	}
+
+	return nil
+}
+
+// addTree archives the content of root under prefix. Missing roots are skipped.
+func addTree(tw *tar.Writer, root string, prefix string, skip func(rel string) bool) error {
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return nil
+	}
+
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if skip != nil && skip(rel) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		link := ""
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		} else if !info.Mode().IsRegular() && !info.IsDir() {
+			// Sockets, devices and pipes cannot be restored meaningfully
+			return nil
+		}
+
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = path.Join(prefix, rel)
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = io.Copy(tw, f)
+		return err
+	})
+}
+
+func writeFile(tw *tar.Writer, name string, data []byte, mode int64) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    mode,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// Restore replaces the team directories, data directory and configuration file
+// with the content of an archive. Non-empty targets are only replaced with force.
+// The configuration of the manager is not reloaded: a restored configuration
+// file applies to the processes started afterwards.
+func (m *Manager) Restore(archivePath string, configPath string, force bool) (Manifest, error) {
+	targets := map[string]string{
+		teamsPrefix: m.config.Paths.Teams,
+		dataPrefix:  m.config.Paths.Data,
+	}
+
+	if !force {
+		for _, target := range targets {
+			empty, err := isEmptyDir(target)
+			if err != nil {
+				return Manifest{}, err
+			}
+			if !empty {
+				return Manifest{}, fmt.Errorf("%s is not empty (use --force to overwrite)", target)
+			}
+		}
+	}
+
+	l, err := m.store.Lock()
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer l.Release()
+
+	// Extract next to each target so that swapping them in is a rename
+	stamp := time.Now().Format("20060102-150405")
+	staging := make(map[string]string)
+	defer func() {
+		for _, dir := range staging {
+			os.RemoveAll(dir)
+		}
+	}()
+	for prefix, target := range targets {
+		dir := filepath.Join(filepath.Dir(target), "."+filepath.Base(target)+".restore-"+stamp)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return Manifest{}, fmt.Errorf("failed to create staging directory: %w", err)
+		}
+		staging[prefix] = dir
+	}
+
+	manifest, configData, err := extract(archivePath, staging)
+	if err != nil {
+		return manifest, err
+	}
+
+	if configData != nil && !force {
+		if _, err := os.Stat(configPath); err == nil {
+			return manifest, fmt.Errorf("configuration %s already exists (use --force to overwrite)", configPath)
+		}
+	}
+
+	// The held lock file moves away with the old data directory: hold the one
+	// of the restored directory too, so no command starts during the swap
+	staged, err := lock.New(filepath.Join(staging[dataPrefix], lock.FileName), m.config.Lock.Timeout).Acquire()
+	if err != nil {
+		return manifest, err
+	}
+	defer staged.Release()
+
+	if err := swap(targets, staging, stamp); err != nil {
+		return manifest, err
+	}
+
+	if configData != nil {
+		if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+			return manifest, fmt.Errorf("failed to restore configuration: %w", err)
+		}
+		if err := os.WriteFile(configPath, configData, 0644); err != nil {
+			return manifest, fmt.Errorf("failed to restore configuration: %w", err)
+		}
+	}
+
+	m.logger.Info("Backup restored", "archive", archivePath, "created", manifest.CreatedAt, "teams", manifest.Teams)
+	return manifest, nil
+}
+
+// extract validates the archive manifest and unpacks each prefix into its staging directory
+func extract(archivePath string, staging map[string]string) (Manifest, []byte, error) {
+	var (
+		manifest   Manifest
+		configData []byte
+	)
+
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return manifest, nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return manifest, nil, fmt.Errorf("invalid archive: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+
+	// The manifest is always the first entry
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != manifestName {
+		return manifest, nil, errors.New("invalid archive: missing manifest")
+	}
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return manifest, nil, fmt.Errorf("invalid archive manifest: %w", err)
+	}
+	if manifest.FormatVersion < 1 || manifest.FormatVersion > FormatVersion {
+		return manifest, nil, fmt.Errorf("unsupported archive format version %d (supported: 1-%d)",
+			manifest.FormatVersion, FormatVersion)
+	}
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return manifest, nil, fmt.Errorf("invalid archive: %w", err)
+		}
+
+		name := path.Clean(hdr.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return manifest, nil, fmt.Errorf("invalid archive: unsafe path %s", hdr.Name)
+		}
+
+		if name == configName {
+			if configData, err = io.ReadAll(tr); err != nil {
+				return manifest, nil, fmt.Errorf("invalid archive: %w", err)
+			}
+			continue
+		}
+
+		prefix, rel, _ := strings.Cut(name, "/")
+		root, ok := staging[prefix]
+		if !ok || rel == "" {
+			return manifest, nil, fmt.Errorf("invalid archive: unexpected entry %s", hdr.Name)
+		}
+
+		if err := extractEntry(tr, hdr, root, rel); err != nil {
+			return manifest, nil, fmt.Errorf("failed to extract %s: %w", hdr.Name, err)
+		}
+	}
+
+	return manifest, configData, nil
+}
+
+// extractEntry unpacks an archive entry at rel, a clean slash-separated path
+// under root. Entries are never written through symlinks, and symlinks may
+// only point inside root.
+func extractEntry(tr *tar.Reader, hdr *tar.Header, root string, rel string) error {
+	mode := fs.FileMode(hdr.Mode).Perm()
+	target := filepath.Join(root, filepath.FromSlash(rel))
+
+	if err := noSymlinks(root, rel); err != nil {
+		return err
+	}
+
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		return os.MkdirAll(target, mode|0700)
+	case tar.TypeSymlink:
+		link := path.Clean(filepath.ToSlash(hdr.Linkname))
+		resolved := path.Join(path.Dir(rel), link)
+		if path.IsAbs(link) || resolved == ".." || strings.HasPrefix(resolved, "../") {
+			return fmt.Errorf("unsafe symlink target %s", hdr.Linkname)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		return os.Symlink(hdr.Linkname, target)
+	case tar.TypeReg:
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(f, tr); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	default:
+		return nil
+	}
+}
+
+// noSymlinks fails if rel, or a directory leading to it under root, is a symlink
+func noSymlinks(root string, rel string) error {
+	current := root
+	for _, part := range strings.Split(rel, "/") {
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("refusing to write through symlink %s", current)
+		}
+	}
+	return nil
+}
+
+// swap replaces each target directory with its staging directory, rolling
+// back the targets already replaced if one of them fails
+func swap(targets map[string]string, staging map[string]string, stamp string) error {
+	type swapped struct{ target, old string }
+	var done []swapped
+
+	rollback := func() {
+		for _, s := range done {
+			os.RemoveAll(s.target)
+			if s.old != "" {
+				os.Rename(s.old, s.target)
+			}
+		}
+	}
+
+	for prefix, target := range targets {
+		old := ""
+		if _, err := os.Stat(target); err == nil {
+			old = target + ".old-" + stamp
+			if err := os.Rename(target, old); err != nil {
+				rollback()
+				return fmt.Errorf("failed to move %s aside: %w", target, err)
+			}
+		}
+
+		if err := os.Rename(staging[prefix], target); err != nil {
+			if old != "" {
+				os.Rename(old, target)
+			}
+			rollback()
+			return fmt.Errorf("failed to restore %s: %w", target, err)
+		}
+		delete(staging, prefix)
+		done = append(done, swapped{target: target, old: old})
+	}
+
+	for _, s := range done {
+		if s.old != "" {
+			os.RemoveAll(s.old)
+		}
+	}
+	return nil
+}
+
+// isEmptyDir reports whether a directory is missing or only holds a lock file.
+// A directory that cannot be read is not assumed empty.
+func isEmptyDir(dir string) (bool, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect %s: %w", dir, err)
+	}
+	for _, entry := range entries {
+		if entry.Name() != lock.FileName {
+			return false, nil
+		}
+	}
+	return true, nil
+}
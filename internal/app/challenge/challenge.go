@@ -6,21 +6,21 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"regexp"
+	"time"
 
 	"github.com/Lolozendev/CTFManager/internal/config"
+	"github.com/Lolozendev/CTFManager/internal/journal"
 	"github.com/Lolozendev/CTFManager/internal/model"
+	"github.com/Lolozendev/CTFManager/internal/store"
 	"github.com/charmbracelet/log"
-)
-
-var (
-	challengeNameRegexp = regexp.MustCompile(`^(?:(\d{1,3})|x)-(\w+)$`)
+	"gopkg.in/yaml.v3"
 )
 
 // Manager handles challenge operations
 type Manager struct {
 	config *config.Config
 	logger *log.Logger
+	store  *store.Store
 }
 
 // New creates a new challenge manager
@@ -28,42 +28,195 @@ func New(cfg *config.Config, logger *log.Logger) *Manager {
 	return &Manager{
 		config: cfg,
 		logger: logger,
+		store:  store.FromConfig(cfg),
 	}
 }
 
-// List returns all challenges found in the challenges directory
+// List returns all registered challenges. Challenges whose challenge.yml
+// cannot be read are skipped with a warning, so one broken challenge does not
+// take the others down.
 func (m *Manager) List() ([]model.Challenge, error) {
+	challenges, broken, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, err := range broken {
+		m.logger.Warn("Skipping broken challenge", "error", err)
+	}
+	return challenges, nil
+}
+
+// load returns the registered challenges whose challenge.yml can be read,
+// and the errors of the others
+func (m *Manager) load() ([]model.Challenge, []error, error) {
+	state, err := m.store.Load()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	challenges := make([]model.Challenge, 0, len(state.Challenges))
+	var broken []error
+	for _, record := range state.Challenges {
+		challenge, err := m.toModel(record)
+		if err != nil {
+			broken = append(broken, err)
+			continue
+		}
+		challenges = append(challenges, challenge)
+	}
+
+	return challenges, broken, nil
+}
+
+// toModel converts a persisted challenge into its model, reading its challenge.yml
+func (m *Manager) toModel(record store.ChallengeRecord) (model.Challenge, error) {
+	challengePath := m.config.GetChallengePath(record.Dir)
+
+	spec, err := loadSpec(challengePath)
+	if err != nil {
+		return model.Challenge{}, fmt.Errorf("challenge %s: %w", record.Name, err)
+	}
+
+	return model.Challenge{
+		Name:        record.Name,
+		NetworkID:   record.NetworkID,
+		BuildPath:   challengePath,
+		EnvPath:     filepath.Join(challengePath, ".env"),
+		Enabled:     record.Enabled,
+		Container:   spec.Container,
+		Healthcheck: spec.Healthcheck,
+		Reset:       spec.Reset,
+		Release:     spec.Release,
+		OnDemand:    spec.OnDemand,
+		Ports:       spec.Ports,
+	}, nil
+}
+
+// Unregistered returns the directories of the challenges path that are not registered challenges
+func (m *Manager) Unregistered() ([]string, error) {
 	entries, err := os.ReadDir(m.config.Paths.Challenges)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read challenges directory: %w", err)
 	}
 
-	var challenges []model.Challenge
+	state, err := m.store.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	registered := make(map[string]bool)
+	for _, record := range state.Challenges {
+		registered[record.Dir] = true
+	}
+
+	var dirs []string
 	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
+		if entry.IsDir() && !registered[entry.Name()] {
+			dirs = append(dirs, entry.Name())
 		}
+	}
 
-		networkID, name, enabled, err := model.ParseChallengeName(entry.Name())
-		if err != nil {
-			m.logger.Warn("Skipping invalid challenge directory", "name", entry.Name(), "error", err)
-			continue
+	return dirs, nil
+}
+
+// Add registers a challenge directory. A zero network ID registers it disabled.
+func (m *Manager) Add(dir string, name string, networkID int) error {
+	if name == "" {
+		name = dir
+	}
+	if err := model.ValidateName(name); err != nil {
+		return err
+	}
+
+	if info, err := os.Stat(m.config.GetChallengePath(dir)); err != nil || !info.IsDir() {
+		return fmt.Errorf("challenge directory %s not found in %s", dir, m.config.Paths.Challenges)
+	}
+
+	err := m.store.Update("challenge.add", func(state *store.State, op *journal.Op) error {
+		if state.Challenge(name) != nil {
+			return fmt.Errorf("challenge %s already exists", name)
+		}
+
+		for _, ch := range state.Challenges {
+			if ch.Dir == dir {
+				return fmt.Errorf("directory %s is already registered as challenge %s", dir, ch.Name)
+			}
 		}
 
-		challengePath := m.config.GetChallengePath(entry.Name())
+		if networkID != 0 {
+			if err := m.checkNetworkID(state, name, networkID); err != nil {
+				return err
+			}
+		}
 
-		challenge := model.Challenge{
+		state.Challenges = append(state.Challenges, store.ChallengeRecord{
 			Name:      name,
 			NetworkID: networkID,
-			BuildPath: challengePath,
-			EnvPath:   filepath.Join(challengePath, ".env"),
-			Enabled:   enabled,
+			Enabled:   networkID != 0,
+			Dir:       dir,
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	m.logger.Info("Challenge added", "name", name, "dir", dir, "networkID", networkID)
+	return nil
+}
+
+// checkNetworkID verifies a network ID is in range and not used by another enabled challenge
+func (m *Manager) checkNetworkID(state *store.State, name string, networkID int) error {
+	if networkID < m.config.Challenges.MinNetworkID || networkID > m.config.Challenges.MaxNetworkID {
+		return fmt.Errorf("invalid network ID %d (must be between %d and %d)",
+			networkID, m.config.Challenges.MinNetworkID, m.config.Challenges.MaxNetworkID)
+	}
+
+	for _, ch := range state.Challenges {
+		if ch.Enabled && ch.NetworkID == networkID && ch.Name != name {
+			return fmt.Errorf("network ID %d is already used by challenge %s", networkID, ch.Name)
 		}
+	}
 
-		challenges = append(challenges, challenge)
+	return nil
+}
+
+// loadSpec reads the optional challenge.yml file of a challenge directory
+func loadSpec(challengePath string) (model.ChallengeSpec, error) {
+	var spec model.ChallengeSpec
+
+	data, err := os.ReadFile(filepath.Join(challengePath, "challenge.yml"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return spec, nil
+		}
+		return spec, fmt.Errorf("failed to read challenge.yml: %w", err)
 	}
 
-	return challenges, nil
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return spec, fmt.Errorf("invalid challenge.yml: %w", err)
+	}
+
+	if spec.Healthcheck != nil {
+		if err := spec.Healthcheck.Validate(); err != nil {
+			return spec, fmt.Errorf("invalid challenge.yml: %w", err)
+		}
+	}
+
+	if spec.Reset != nil {
+		if _, err := spec.Reset.Schedule(); err != nil {
+			return spec, fmt.Errorf("invalid challenge.yml: %w", err)
+		}
+	}
+
+	for _, port := range spec.Ports {
+		if _, _, err := model.ParsePort(port); err != nil {
+			return spec, fmt.Errorf("invalid challenge.yml: %w", err)
+		}
+	}
+
+	return spec, nil
 }
 
 // ListEnabled returns only enabled challenges
@@ -83,12 +236,39 @@ func (m *Manager) ListEnabled() ([]model.Challenge, error) {
 	return enabled, nil
 }
 
+// ListReleased returns enabled challenges whose release time has passed
+func (m *Manager) ListReleased(now time.Time) ([]model.Challenge, error) {
+	enabledChallenges, err := m.ListEnabled()
+	if err != nil {
+		return nil, err
+	}
+
+	var released []model.Challenge
+	for _, ch := range enabledChallenges {
+		if ch.Released(now) {
+			released = append(released, ch)
+		}
+	}
+
+	return released, nil
+}
+
 // Validate checks all challenges for correctness
 func (m *Manager) Validate() error {
-	challenges, err := m.ListEnabled()
+	all, broken, err := m.load()
 	if err != nil {
 		return err
 	}
+	if len(broken) > 0 {
+		return errors.Join(broken...)
+	}
+
+	var challenges []model.Challenge
+	for _, ch := range all {
+		if ch.Enabled {
+			challenges = append(challenges, ch)
+		}
+	}
 
 	if len(challenges) == 0 {
 		return errors.New("no enabled challenges found")
@@ -142,62 +322,45 @@ func (m *Manager) validateChallengeStructure(ch model.Challenge) error {
 	return nil
 }
 
-// Enable enables a challenge by renaming its directory
+// Enable enables a challenge with the given network ID
 func (m *Manager) Enable(name string, networkID int) error {
-	// Find the disabled challenge
-	oldPath := m.config.GetChallengePath(fmt.Sprintf("x-%s", name))
-	if _, err := os.Stat(oldPath); os.IsNotExist(err) {
-		return fmt.Errorf("disabled challenge %s not found", name)
-	}
-
-	// Check if network ID is available
-	challenges, err := m.ListEnabled()
-	if err != nil {
-		return err
-	}
+	err := m.store.Update("challenge.enable", func(state *store.State, op *journal.Op) error {
+		found := state.Challenge(name)
+		if found == nil || found.Enabled {
+			return fmt.Errorf("disabled challenge %s not found", name)
+		}
 
-	for _, ch := range challenges {
-		if ch.NetworkID == networkID {
-			return fmt.Errorf("network ID %d is already used by challenge %s", networkID, ch.Name)
+		// Check if network ID is available
+		if err := m.checkNetworkID(state, name, networkID); err != nil {
+			return err
 		}
-	}
 
-	// Rename directory
-	newPath := m.config.GetChallengePath(model.FormatChallengeName(networkID, name, true))
-	if err := os.Rename(oldPath, newPath); err != nil {
-		return fmt.Errorf("failed to enable challenge: %w", err)
+		found.Enabled = true
+		found.NetworkID = networkID
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	m.logger.Info("Challenge enabled", "name", name, "networkID", networkID)
 	return nil
 }
 
-// Disable disables a challenge by renaming its directory
+// Disable disables a challenge
 func (m *Manager) Disable(name string) error {
-	// Find the enabled challenge
-	challenges, err := m.ListEnabled()
-	if err != nil {
-		return err
-	}
-
-	var found *model.Challenge
-	for _, ch := range challenges {
-		if ch.Name == name {
-			found = &ch
-			break
+	err := m.store.Update("challenge.disable", func(state *store.State, op *journal.Op) error {
+		found := state.Challenge(name)
+		if found == nil || !found.Enabled {
+			return fmt.Errorf("enabled challenge %s not found", name)
 		}
-	}
-
-	if found == nil {
-		return fmt.Errorf("enabled challenge %s not found", name)
-	}
 
-	// Rename directory
-	oldPath := found.BuildPath
-	newPath := m.config.GetChallengePath(model.FormatChallengeName(0, name, false))
-
-	if err := os.Rename(oldPath, newPath); err != nil {
-		return fmt.Errorf("failed to disable challenge: %w", err)
+		found.Enabled = false
+		found.NetworkID = 0
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	m.logger.Info("Challenge disabled", "name", name)
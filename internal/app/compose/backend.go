@@ -0,0 +1,78 @@
+package compose
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/Lolozendev/CTFManager/internal/app/compose/kubernetes"
+	"github.com/Lolozendev/CTFManager/internal/app/compose/quadlet"
+	"github.com/Lolozendev/CTFManager/internal/config"
+	"github.com/Lolozendev/CTFManager/internal/model"
+	"github.com/charmbracelet/log"
+	"gopkg.in/yaml.v3"
+)
+
+// Backend renders the stack of a team for an orchestrator
+type Backend interface {
+	// Render returns the files describing the stack of a team
+	Render(team model.Team, stack model.ComposeFile) ([]File, error)
+}
+
+// File is a rendered file, by path relative to the team directory. Files in
+// subdirectories are owned by the backend: stale ones are removed on write.
+type File struct {
+	Path string
+	Data []byte
+}
+
+// newBackend returns the backend selected by the configuration
+func newBackend(cfg *config.Config, logger *log.Logger) Backend {
+	switch cfg.Backend {
+	case config.BackendKubernetes:
+		return kubernetesBackend{renderer: kubernetes.New(cfg.Kubernetes.Registry, logger)}
+	case config.BackendQuadlet:
+		return quadletBackend{}
+	default:
+		return composeBackend{}
+	}
+}
+
+// composeBackend renders a compose.yml started with docker compose
+type composeBackend struct{}
+
+func (composeBackend) Render(team model.Team, stack model.ComposeFile) ([]File, error) {
+	data, err := yaml.Marshal(&stack)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal compose file: %w", err)
+	}
+	return []File{{Path: "compose.yml", Data: data}}, nil
+}
+
+// kubernetesBackend renders a kubernetes.yml applied with kubectl
+type kubernetesBackend struct {
+	renderer *kubernetes.Renderer
+}
+
+func (b kubernetesBackend) Render(team model.Team, stack model.ComposeFile) ([]File, error) {
+	data, err := b.renderer.Render(team, stack)
+	if err != nil {
+		return nil, err
+	}
+	return []File{{Path: "kubernetes.yml", Data: data}}, nil
+}
+
+// quadletBackend renders Podman Quadlet units into the quadlet directory of the team
+type quadletBackend struct{}
+
+func (quadletBackend) Render(team model.Team, stack model.ComposeFile) ([]File, error) {
+	units, err := quadlet.Render(team, stack)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]File, 0, len(units))
+	for _, unit := range units {
+		files = append(files, File{Path: filepath.Join("quadlet", unit.Name), Data: unit.Data})
+	}
+	return files, nil
+}
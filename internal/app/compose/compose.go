@@ -1,8 +1,11 @@
-// Package compose generates Docker Compose files for CTF teams
+// Package compose generates the stacks of CTF teams, rendered as Docker
+// Compose files or by another backend
 package compose
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 
 	"github.com/Lolozendev/CTFManager/internal/config"
 	"github.com/Lolozendev/CTFManager/internal/model"
@@ -10,29 +13,131 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-// Generator handles Docker Compose file generation
+// Generator handles team stack generation
 type Generator struct {
-	config *config.Config
-	logger *log.Logger
+	config  *config.Config
+	logger  *log.Logger
+	backend Backend
 }
 
-// New creates a new compose generator
+// New creates a new compose generator rendering through the configured backend
 func New(cfg *config.Config, logger *log.Logger) *Generator {
 	return &Generator{
-		config: cfg,
-		logger: logger,
+		config:  cfg,
+		logger:  logger,
+		backend: newBackend(cfg, logger),
 	}
 }
 
-// Generate creates a Docker Compose YAML for a team
-func (g *Generator) Generate(team model.Team, challenges []model.Challenge) (string, error) {
-	composeFile := model.NewComposeFile(team, challenges)
+// Generate renders the stack of a team into the files of the backend
+func (g *Generator) Generate(team model.Team, challenges []model.Challenge) ([]File, error) {
+	return g.backend.Render(team, g.Stack(team, challenges))
+}
+
+// Stack describes the services and networks of a team
+func (g *Generator) Stack(team model.Team, challenges []model.Challenge) model.ComposeFile {
+	// Apply global container defaults, overridden by each challenge's own settings
+	effective := make([]model.Challenge, 0, len(challenges))
+	game := g.GameNetwork()
+	for _, ch := range challenges {
+		ch.Container = g.config.Containers.Merge(ch.Container)
+		switch {
+		case g.config.Mode == config.ModeAttackDefense && ch.Name == g.config.AttackDefense.Vulnbox:
+			game.Vulnbox = &ch
+		case g.config.Mode == config.ModeKingOfTheHill && ch.Name == g.config.KingOfTheHill.Challenge:
+			// Deployed once for every team by WriteHill
+		default:
+			effective = append(effective, ch)
+		}
+	}
+
+	if g.config.Mode == config.ModeAttackDefense && game.Vulnbox == nil {
+		g.logger.Warn("Vulnbox challenge not released, generating without it",
+			"team", team.Name, "challenge", g.config.AttackDefense.Vulnbox)
+	}
+
+	return model.NewComposeFile(team, effective, model.ComposeOptions{
+		Network: g.config.TeamAddressing(team.ID),
+		VPNHost: g.config.GetVPNAddress(team.Host),
+		VPNPort: g.config.GetVPNPort(team.ID),
+		Game:    game,
+	})
+}
+
+// GameNetwork returns the shared game network in attack-defense and
+// king-of-the-hill modes, nil otherwise
+func (g *Generator) GameNetwork() *model.GameNetwork {
+	if g.config.Mode != config.ModeAttackDefense && g.config.Mode != config.ModeKingOfTheHill {
+		return nil
+	}
+	return g.config.GameNetwork()
+}
+
+// WriteHill writes the compose file of the king-of-the-hill challenge, deployed
+// once on the game network, into a project directory
+func (g *Generator) WriteHill(projectDir string, hill model.Challenge) (string, error) {
+	hill.Container = g.config.Containers.Merge(hill.Container)
+	composeFile := model.NewHillComposeFile(hill, *g.config.GameNetwork())
 
-	// Marshal to YAML
 	data, err := yaml.Marshal(&composeFile)
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal compose file: %w", err)
 	}
 
-	return string(data), nil
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create project directory: %w", err)
+	}
+
+	composePath := filepath.Join(projectDir, "compose.yml")
+	if err := os.WriteFile(composePath, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write compose file: %w", err)
+	}
+
+	g.logger.Debug("Hill compose file written", "challenge", hill.Name, "path", composePath)
+	return composePath, nil
+}
+
+// Write generates the files of a team stack, writes them into the team
+// directory and returns the path of the first one
+func (g *Generator) Write(team model.Team, challenges []model.Challenge) (string, error) {
+	files, err := g.Generate(team, challenges)
+	if err != nil {
+		return "", err
+	}
+
+	written := make(map[string]bool)
+	dirs := make(map[string]bool)
+	for _, file := range files {
+		path := filepath.Join(team.Path, file.Path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return "", fmt.Errorf("failed to create directory of %s: %w", file.Path, err)
+		}
+		if err := os.WriteFile(path, file.Data, 0644); err != nil {
+			return "", fmt.Errorf("failed to write %s: %w", file.Path, err)
+		}
+		written[path] = true
+		if dir := filepath.Dir(path); dir != filepath.Clean(team.Path) {
+			dirs[dir] = true
+		}
+	}
+
+	// Drop the files of services removed since the last write
+	for dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return "", fmt.Errorf("failed to list %s: %w", dir, err)
+		}
+		for _, entry := range entries {
+			stale := filepath.Join(dir, entry.Name())
+			if entry.Type().IsRegular() && !written[stale] {
+				if err := os.Remove(stale); err != nil {
+					return "", fmt.Errorf("failed to remove %s: %w", stale, err)
+				}
+			}
+		}
+	}
+
+	path := filepath.Join(team.Path, files[0].Path)
+	g.logger.Debug("Stack written", "team", team.Name, "path", path, "files", len(files), "challenges", len(challenges))
+	return path, nil
 }
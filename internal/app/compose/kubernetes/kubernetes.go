@@ -0,0 +1,431 @@
+// Package kubernetes renders team stacks as Kubernetes manifests
+package kubernetes
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Lolozendev/CTFManager/internal/model"
+	"github.com/charmbracelet/log"
+	"gopkg.in/yaml.v3"
+)
+
+// Labels set on generated objects
+const (
+	labelTeam = "ctfmanager/team"
+	labelApp  = "app.kubernetes.io/name"
+)
+
+// Renderer renders the stack of a team as manifests applied with kubectl: a
+// Namespace per team, a Deployment and a Service per compose service, and
+// NetworkPolicies only letting the team's VPN in
+type Renderer struct {
+	registry string
+	logger   *log.Logger
+}
+
+// New creates a renderer pulling challenge images from a registry, or using
+// local images named after their challenge when registry is empty
+func New(registry string, logger *log.Logger) *Renderer {
+	return &Renderer{
+		registry: registry,
+		logger:   logger,
+	}
+}
+
+// Namespace returns the namespace of a team
+func Namespace(team string) string {
+	return "ctf-" + objectName(team)
+}
+
+// Render returns the manifests of a team stack as a multi-document YAML file
+func (r *Renderer) Render(team model.Team, stack model.ComposeFile) ([]byte, error) {
+	namespace := Namespace(team.Name)
+	networkName := team.Name + "-Network"
+
+	objects := []Object{{
+		APIVersion: "v1",
+		Kind:       "Namespace",
+		Metadata:   Metadata{Name: namespace, Labels: map[string]string{labelTeam: team.Name}},
+	}}
+
+	for _, key := range slices.Sorted(maps.Keys(stack.Services)) {
+		service := stack.Services[key]
+		addr, ok := service.Networks[networkName]
+		if !ok {
+			return nil, fmt.Errorf("service %s is not on the team network, only jeopardy stacks can be rendered", key)
+		}
+
+		rendered, err := r.service(team, namespace, key, service, addr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render service %s: %w", key, err)
+		}
+		objects = append(objects, rendered...)
+	}
+
+	objects = append(objects, r.policies(namespace, stack)...)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# Generated by ctfmanager for team %s, apply with kubectl apply -f\n", team.Name)
+	for _, obj := range objects {
+		data, err := yaml.Marshal(&obj)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s %s: %w", obj.Kind, obj.Metadata.Name, err)
+		}
+		buf.WriteString("---\n")
+		buf.Write(data)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// service renders the Deployment of a compose service, its Service and the
+// objects backing its volumes and env file
+func (r *Renderer) service(team model.Team, namespace, key string, service model.Service, addr model.IPAddr) ([]Object, error) {
+	name := objectName(key)
+	labels := map[string]string{labelApp: name, labelTeam: team.Name}
+	meta := func(suffix string) Metadata {
+		return Metadata{Name: name + suffix, Namespace: namespace, Labels: labels}
+	}
+
+	var objects []Object
+
+	image := service.Image
+	if image == "" {
+		image = name
+		if r.registry != "" {
+			image = strings.TrimSuffix(r.registry, "/") + "/" + name
+		}
+	}
+
+	container := Container{
+		Name:      name,
+		Image:     image,
+		Resources: resources(service.Deploy),
+	}
+	for _, env := range service.Environment {
+		k, v, _ := strings.Cut(env, "=")
+		container.Env = append(container.Env, EnvVar{Name: k, Value: v})
+	}
+
+	if service.EnvFile != "" {
+		env, err := readEnvFile(service.EnvFile)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if len(env) > 0 {
+			objects = append(objects, Object{APIVersion: "v1", Kind: "Secret", Metadata: meta("-env"), StringData: env})
+			container.EnvFrom = []EnvFrom{{SecretRef: LocalRef{Name: name + "-env"}}}
+		}
+	}
+
+	// Security settings
+	security := &SecurityContext{ReadOnlyRootFilesystem: service.ReadOnly}
+	if slices.Contains(service.SecurityOpt, "no-new-privileges:true") {
+		noEscalation := false
+		security.AllowPrivilegeEscalation = &noEscalation
+	}
+	if len(service.CapAdd) > 0 {
+		security.Capabilities = &Capabilities{Add: service.CapAdd}
+	}
+	if *security != (SecurityContext{}) {
+		container.SecurityContext = security
+	}
+
+	pod := PodSpec{}
+	if len(service.Sysctls) > 0 {
+		pod.SecurityContext = &PodSecurityContext{}
+		for _, k := range slices.Sorted(maps.Keys(service.Sysctls)) {
+			pod.SecurityContext.Sysctls = append(pod.SecurityContext.Sysctls, Sysctl{Name: k, Value: service.Sysctls[k]})
+		}
+	}
+
+	// Volumes
+	var strategy *Strategy
+	for i, tmpfs := range service.Tmpfs {
+		volume := fmt.Sprintf("tmpfs-%d", i)
+		pod.Volumes = append(pod.Volumes, Volume{Name: volume, EmptyDir: &EmptyDir{Medium: "Memory"}})
+		container.VolumeMounts = append(container.VolumeMounts, VolumeMount{Name: volume, MountPath: tmpfs})
+	}
+	for _, spec := range service.Volumes {
+		source, target, _ := strings.Cut(spec, ":")
+		target, _, _ = strings.Cut(target, ":")
+		if !strings.HasPrefix(source, "./") {
+			return nil, fmt.Errorf("unsupported volume %s, only paths of the team directory can be rendered", spec)
+		}
+
+		volume := name + "-" + objectName(filepath.Base(source))
+		info, err := os.Stat(filepath.Join(team.Path, source))
+		if err == nil && !info.IsDir() {
+			// Configuration file, shipped as a ConfigMap
+			data, err := os.ReadFile(filepath.Join(team.Path, source))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", source, err)
+			}
+			file := filepath.Base(source)
+			objects = append(objects, Object{
+				APIVersion: "v1",
+				Kind:       "ConfigMap",
+				Metadata:   Metadata{Name: volume, Namespace: namespace, Labels: labels},
+				Data:       map[string]string{file: string(data)},
+			})
+			pod.Volumes = append(pod.Volumes, Volume{Name: volume, ConfigMap: &LocalRef{Name: volume}})
+			container.VolumeMounts = append(container.VolumeMounts, VolumeMount{Name: volume, MountPath: target, SubPath: file})
+			continue
+		}
+
+		// State directory, kept on a persistent volume
+		objects = append(objects, Object{
+			APIVersion: "v1",
+			Kind:       "PersistentVolumeClaim",
+			Metadata:   Metadata{Name: volume, Namespace: namespace, Labels: labels},
+			Spec: ClaimSpec{
+				AccessModes: []string{"ReadWriteOnce"},
+				Resources:   Resources{Requests: map[string]string{"storage": "64Mi"}},
+			},
+		})
+		pod.Volumes = append(pod.Volumes, Volume{Name: volume, PersistentVolumeClaim: &ClaimRef{ClaimName: volume}})
+		container.VolumeMounts = append(container.VolumeMounts, VolumeMount{Name: volume, MountPath: target})
+		strategy = &Strategy{Type: "Recreate"}
+	}
+
+	// Ports, served at the team network address of the service
+	servicePorts, err := ports(service)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range servicePorts {
+		container.Ports = append(container.Ports, ContainerPort{ContainerPort: p.TargetPort, Protocol: p.Protocol})
+	}
+
+	if service.Healthcheck != nil {
+		container.LivenessProbe = probe(*service.Healthcheck)
+	}
+
+	pod.Containers = []Container{container}
+
+	replicas := 1
+	if slices.Contains(service.Profiles, model.OnDemandProfile) {
+		replicas = 0
+	}
+
+	objects = append(objects, Object{
+		APIVersion: "apps/v1",
+		Kind:       "Deployment",
+		Metadata:   meta(""),
+		Spec: DeploymentSpec{
+			Replicas: replicas,
+			Selector: LabelSelector{MatchLabels: map[string]string{labelApp: name}},
+			Strategy: strategy,
+			Template: PodTemplate{Metadata: Metadata{Name: name, Labels: labels}, Spec: pod},
+		},
+	})
+
+	if len(servicePorts) == 0 {
+		r.logger.Warn("Service without ports left unreachable, list them in challenge.yml", "team", team.Name, "service", key)
+		return objects, nil
+	}
+
+	spec := ServiceSpec{
+		ClusterIP: addr.Ipv4Address,
+		Selector:  map[string]string{labelApp: name},
+		Ports:     servicePorts,
+	}
+	if addr.Ipv6Address != "" {
+		spec.ClusterIPs = []string{addr.Ipv4Address, addr.Ipv6Address}
+		spec.IPFamilyPolicy = "PreferDualStack"
+	}
+	if len(service.Ports) > 0 {
+		spec.Type = "LoadBalancer"
+	}
+	objects = append(objects, Object{APIVersion: "v1", Kind: "Service", Metadata: meta(""), Spec: spec})
+
+	return objects, nil
+}
+
+// policies isolates the team namespace: pods only accept traffic from the
+// namespace itself, and from anywhere on the ports published by the stack
+func (r *Renderer) policies(namespace string, stack model.ComposeFile) []Object {
+	objects := []Object{{
+		APIVersion: "networking.k8s.io/v1",
+		Kind:       "NetworkPolicy",
+		Metadata:   Metadata{Name: "team-isolation", Namespace: namespace},
+		Spec: NetworkPolicySpec{
+			PodSelector: LabelSelector{},
+			PolicyTypes: []string{"Ingress"},
+			Ingress:     []IngressRule{{From: []PolicyPeer{{PodSelector: &LabelSelector{}}}}},
+		},
+	}}
+
+	for _, key := range slices.Sorted(maps.Keys(stack.Services)) {
+		service := stack.Services[key]
+		if len(service.Ports) == 0 {
+			continue
+		}
+
+		var policyPorts []PolicyPort
+		for _, published := range service.Ports {
+			p, err := parsePublished(published)
+			if err == nil {
+				policyPorts = append(policyPorts, PolicyPort{Port: p.TargetPort, Protocol: p.Protocol})
+			}
+		}
+		objects = append(objects, Object{
+			APIVersion: "networking.k8s.io/v1",
+			Kind:       "NetworkPolicy",
+			Metadata:   Metadata{Name: objectName(key) + "-public", Namespace: namespace},
+			Spec: NetworkPolicySpec{
+				PodSelector: LabelSelector{MatchLabels: map[string]string{labelApp: objectName(key)}},
+				PolicyTypes: []string{"Ingress"},
+				Ingress:     []IngressRule{{Ports: policyPorts}},
+			},
+		})
+	}
+
+	return objects
+}
+
+// ports returns the Service ports of a compose service: its exposed ports,
+// and its published ports on their host port number
+func ports(service model.Service) ([]ServicePort, error) {
+	var result []ServicePort
+	for _, exposed := range service.Expose {
+		n, protocol, err := model.ParsePort(exposed)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, ServicePort{
+			Name:       fmt.Sprintf("%s-%d", protocol, n),
+			Port:       n,
+			TargetPort: n,
+			Protocol:   strings.ToUpper(protocol),
+		})
+	}
+	for _, published := range service.Ports {
+		p, err := parsePublished(published)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, p)
+	}
+	return result, nil
+}
+
+// parsePublished parses a compose published port, e.g. "50001:51820/udp"
+func parsePublished(published string) (ServicePort, error) {
+	host, target, found := strings.Cut(published, ":")
+	if !found {
+		return ServicePort{}, fmt.Errorf("invalid published port %q", published)
+	}
+	hostPort, _, err := model.ParsePort(host)
+	if err != nil {
+		return ServicePort{}, err
+	}
+	targetPort, protocol, err := model.ParsePort(target)
+	if err != nil {
+		return ServicePort{}, err
+	}
+	return ServicePort{
+		Name:       fmt.Sprintf("public-%d", hostPort),
+		Port:       hostPort,
+		TargetPort: targetPort,
+		Protocol:   strings.ToUpper(protocol),
+	}, nil
+}
+
+// resources converts compose resource limits, Kubernetes has no per-container PID limit
+func resources(deploy *model.Deploy) *Resources {
+	if deploy == nil {
+		return nil
+	}
+
+	res := &Resources{}
+	if limits := deploy.Resources.Limits; limits != nil {
+		res.Limits = map[string]string{}
+		if limits.CPUs != "" {
+			res.Limits["cpu"] = limits.CPUs
+		}
+		if limits.Memory != "" {
+			res.Limits["memory"] = quantity(limits.Memory)
+		}
+	}
+	if reservations := deploy.Resources.Reservations; reservations != nil && reservations.Memory != "" {
+		res.Requests = map[string]string{"memory": quantity(reservations.Memory)}
+	}
+	return res
+}
+
+// quantity converts a Docker memory size, e.g. "512m", to a Kubernetes quantity
+func quantity(size string) string {
+	lower := strings.ToLower(size)
+	for suffix, unit := range map[string]string{"k": "Ki", "m": "Mi", "g": "Gi"} {
+		if number, ok := strings.CutSuffix(lower, suffix); ok {
+			return number + unit
+		}
+	}
+	return strings.TrimSuffix(lower, "b")
+}
+
+// probe converts a compose healthcheck into a liveness probe
+func probe(hc model.ComposeHealthcheck) *Probe {
+	if len(hc.Test) != 2 || hc.Test[0] != "CMD-SHELL" {
+		return nil
+	}
+
+	p := &Probe{
+		Exec:             ExecAction{Command: []string{"sh", "-c", hc.Test[1]}},
+		FailureThreshold: hc.Retries,
+	}
+	if d, err := time.ParseDuration(hc.Interval); err == nil {
+		p.PeriodSeconds = max(1, int(d.Seconds()))
+	}
+	if d, err := time.ParseDuration(hc.Timeout); err == nil {
+		p.TimeoutSeconds = max(1, int(d.Seconds()))
+	}
+	return p
+}
+
+// readEnvFile reads the KEY=VALUE lines of an env file
+func readEnvFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read env file: %w", err)
+	}
+	defer file.Close()
+
+	env := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if k, v, ok := strings.Cut(line, "="); ok {
+			env[strings.TrimSpace(k)] = strings.Trim(strings.TrimSpace(v), `"'`)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return env, nil
+}
+
+var invalidName = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// objectName turns a team or challenge name into a valid Kubernetes object name
+func objectName(name string) string {
+	name = strings.Trim(invalidName.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if name == "" || name[0] < 'a' || name[0] > 'z' {
+		name = "x-" + name
+	}
+	return name
+}
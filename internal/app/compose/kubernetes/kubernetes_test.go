@@ -0,0 +1,114 @@
+package kubernetes
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Lolozendev/CTFManager/internal/ipam"
+	"github.com/Lolozendev/CTFManager/internal/model"
+	"github.com/charmbracelet/log"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+// render renders the stack of team alpha (ID 1) with a web challenge, reading
+// an env file, and an on-demand pwn challenge whose env file does not exist
+func render(t *testing.T, ipv6 bool) []byte {
+	t.Helper()
+
+	plan, err := ipam.New("10.0.0.0/16", 24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ipv6 {
+		if err := plan.EnableIPv6("fd00:c7f::/48"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	addr := model.TeamAddressing{IPv4: plan.Team(1)}
+	if v6, ok := plan.Team6(1); ok {
+		addr.IPv6 = &v6
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"dns/dnsmasq.conf":             "address=/web/10.0.1.11\n",
+		model.WireguardServerTemplate:  "[Interface]\n",
+		model.WireguardPeerTemplate:    "[Interface]\n",
+		"challenges/web/.env":          "# comment\nFLAG=CTF{test}\n",
+		"challenges/pwn/challenge.yml": "on_demand: true\n",
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	team := model.Team{ID: 1, Name: "alpha", Members: []model.Member{{Username: "ada"}, {Username: "bob"}}, Path: dir}
+	challenges := []model.Challenge{
+		{
+			Name:      "web",
+			NetworkID: 11,
+			BuildPath: filepath.Join(dir, "challenges/web"),
+			EnvPath:   filepath.Join(dir, "challenges/web/.env"),
+			Ports:     []string{"80"},
+		},
+		{
+			Name:      "pwn",
+			NetworkID: 12,
+			BuildPath: filepath.Join(dir, "challenges/pwn"),
+			EnvPath:   filepath.Join(dir, "challenges/pwn/.env"),
+			OnDemand:  true,
+			Ports:     []string{"1337"},
+		},
+	}
+	stack := model.NewComposeFile(team, challenges, model.ComposeOptions{
+		Network: addr,
+		VPNHost: "203.0.113.10",
+		VPNPort: 50001,
+	})
+
+	data, err := New("registry.example.com/ctf", log.New(io.Discard)).Render(team, stack)
+	if err != nil {
+		t.Fatalf("Render() failed: %v", err)
+	}
+	return data
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		ipv6 bool
+	}{
+		{"jeopardy", false},
+		{"dual-stack", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := render(t, tt.ipv6)
+
+			golden := filepath.Join("testdata", tt.name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("failed to read golden file, run go test -update: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("Render() differs from %s, run go test -update and review the diff:\n%s", golden, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package kubernetes
+
+// Metadata is the metadata of a Kubernetes object
+type Metadata struct {
+	Name      string            `yaml:"name"`
+	Namespace string            `yaml:"namespace,omitempty"`
+	Labels    map[string]string `yaml:"labels,omitempty"`
+}
+
+// Object is a Kubernetes object with its type
+type Object struct {
+	APIVersion string   `yaml:"apiVersion"`
+	Kind       string   `yaml:"kind"`
+	Metadata   Metadata `yaml:"metadata"`
+
+	Spec       any               `yaml:"spec,omitempty"`
+	Data       map[string]string `yaml:"data,omitempty"`       // ConfigMap
+	StringData map[string]string `yaml:"stringData,omitempty"` // Secret
+}
+
+// DeploymentSpec is the spec of a Deployment
+type DeploymentSpec struct {
+	Replicas int           `yaml:"replicas"`
+	Selector LabelSelector `yaml:"selector"`
+	Strategy *Strategy     `yaml:"strategy,omitempty"`
+	Template PodTemplate   `yaml:"template"`
+}
+
+// Strategy is the update strategy of a Deployment
+type Strategy struct {
+	Type string `yaml:"type"`
+}
+
+// LabelSelector selects objects by label
+type LabelSelector struct {
+	MatchLabels map[string]string `yaml:"matchLabels"`
+}
+
+// PodTemplate describes the pods of a Deployment
+type PodTemplate struct {
+	Metadata Metadata `yaml:"metadata"`
+	Spec     PodSpec  `yaml:"spec"`
+}
+
+// PodSpec is the spec of a pod
+type PodSpec struct {
+	Containers      []Container         `yaml:"containers"`
+	Volumes         []Volume            `yaml:"volumes,omitempty"`
+	SecurityContext *PodSecurityContext `yaml:"securityContext,omitempty"`
+}
+
+// PodSecurityContext holds pod-wide security settings
+type PodSecurityContext struct {
+	Sysctls []Sysctl `yaml:"sysctls,omitempty"`
+}
+
+// Sysctl is a kernel parameter set in the pod namespaces
+type Sysctl struct {
+	Name  string `yaml:"name"`
+	Value string `yaml:"value"`
+}
+
+// Container is a container of a pod
+type Container struct {
+	Name            string           `yaml:"name"`
+	Image           string           `yaml:"image"`
+	Env             []EnvVar         `yaml:"env,omitempty"`
+	EnvFrom         []EnvFrom        `yaml:"envFrom,omitempty"`
+	Ports           []ContainerPort  `yaml:"ports,omitempty"`
+	Resources       *Resources       `yaml:"resources,omitempty"`
+	SecurityContext *SecurityContext `yaml:"securityContext,omitempty"`
+	VolumeMounts    []VolumeMount    `yaml:"volumeMounts,omitempty"`
+	LivenessProbe   *Probe           `yaml:"livenessProbe,omitempty"`
+}
+
+// EnvVar is an environment variable of a container
+type EnvVar struct {
+	Name  string `yaml:"name"`
+	Value string `yaml:"value"`
+}
+
+// EnvFrom loads environment variables from a Secret
+type EnvFrom struct {
+	SecretRef LocalRef `yaml:"secretRef"`
+}
+
+// LocalRef references an object of the same namespace
+type LocalRef struct {
+	Name string `yaml:"name"`
+}
+
+// ContainerPort is a port a container listens on
+type ContainerPort struct {
+	ContainerPort int    `yaml:"containerPort"`
+	Protocol      string `yaml:"protocol"`
+}
+
+// Resources holds the resource limits and requests of a container
+type Resources struct {
+	Limits   map[string]string `yaml:"limits,omitempty"`
+	Requests map[string]string `yaml:"requests,omitempty"`
+}
+
+// SecurityContext holds the security settings of a container
+type SecurityContext struct {
+	ReadOnlyRootFilesystem   bool          `yaml:"readOnlyRootFilesystem,omitempty"`
+	AllowPrivilegeEscalation *bool         `yaml:"allowPrivilegeEscalation,omitempty"`
+	Capabilities             *Capabilities `yaml:"capabilities,omitempty"`
+}
+
+// Capabilities lists the Linux capabilities added to a container
+type Capabilities struct {
+	Add []string `yaml:"add"`
+}
+
+// VolumeMount mounts a volume in a container
+type VolumeMount struct {
+	Name      string `yaml:"name"`
+	MountPath string `yaml:"mountPath"`
+	SubPath   string `yaml:"subPath,omitempty"`
+}
+
+// Volume is a volume of a pod, backed by one of its sources
+type Volume struct {
+	Name                  string    `yaml:"name"`
+	EmptyDir              *EmptyDir `yaml:"emptyDir,omitempty"`
+	ConfigMap             *LocalRef `yaml:"configMap,omitempty"`
+	PersistentVolumeClaim *ClaimRef `yaml:"persistentVolumeClaim,omitempty"`
+}
+
+// EmptyDir is a scratch volume living as long as its pod
+type EmptyDir struct {
+	Medium string `yaml:"medium,omitempty"`
+}
+
+// ClaimRef references a PersistentVolumeClaim
+type ClaimRef struct {
+	ClaimName string `yaml:"claimName"`
+}
+
+// Probe runs a command to check a container
+type Probe struct {
+	Exec             ExecAction `yaml:"exec"`
+	PeriodSeconds    int        `yaml:"periodSeconds,omitempty"`
+	TimeoutSeconds   int        `yaml:"timeoutSeconds,omitempty"`
+	FailureThreshold int        `yaml:"failureThreshold,omitempty"`
+}
+
+// ExecAction is a command run in a container
+type ExecAction struct {
+	Command []string `yaml:"command"`
+}
+
+// ServiceSpec is the spec of a Service
+type ServiceSpec struct {
+	Type           string            `yaml:"type,omitempty"`
+	ClusterIP      string            `yaml:"clusterIP,omitempty"`
+	ClusterIPs     []string          `yaml:"clusterIPs,omitempty"`
+	IPFamilyPolicy string            `yaml:"ipFamilyPolicy,omitempty"`
+	Selector       map[string]string `yaml:"selector"`
+	Ports          []ServicePort     `yaml:"ports"`
+}
+
+// ServicePort is a port of a Service
+type ServicePort struct {
+	Name       string `yaml:"name"`
+	Port       int    `yaml:"port"`
+	TargetPort int    `yaml:"targetPort"`
+	Protocol   string `yaml:"protocol"`
+}
+
+// ClaimSpec is the spec of a PersistentVolumeClaim
+type ClaimSpec struct {
+	AccessModes []string  `yaml:"accessModes"`
+	Resources   Resources `yaml:"resources"`
+}
+
+// NetworkPolicySpec is the spec of a NetworkPolicy
+type NetworkPolicySpec struct {
+	PodSelector LabelSelector `yaml:"podSelector"`
+	PolicyTypes []string      `yaml:"policyTypes"`
+	Ingress     []IngressRule `yaml:"ingress"`
+}
+
+// IngressRule allows traffic from peers to ports; empty fields allow everything
+type IngressRule struct {
+	From  []PolicyPeer `yaml:"from,omitempty"`
+	Ports []PolicyPort `yaml:"ports,omitempty"`
+}
+
+// PolicyPeer selects the sources of allowed traffic
+type PolicyPeer struct {
+	PodSelector *LabelSelector `yaml:"podSelector,omitempty"`
+}
+
+// PolicyPort is a port allowed by a NetworkPolicy
+type PolicyPort struct {
+	Port     int    `yaml:"port"`
+	Protocol string `yaml:"protocol"`
+}
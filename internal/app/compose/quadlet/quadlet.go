@@ -0,0 +1,202 @@
+// Package quadlet renders team stacks as Podman Quadlet units
+package quadlet
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/Lolozendev/CTFManager/internal/model"
+)
+
+// Unit is a Quadlet unit file
+type Unit struct {
+	Name string // File name, e.g. "alpha-web.container"
+	Data []byte
+}
+
+// Render returns the units of a team stack: a .network unit for the team
+// network, and a .container unit per compose service, with a .build unit
+// when its image is built from a challenge directory
+func Render(team model.Team, stack model.ComposeFile) ([]Unit, error) {
+	networkName := team.Name + "-Network"
+	network, ok := stack.Networks[networkName]
+	if !ok {
+		return nil, fmt.Errorf("stack of team %s has no team network", team.Name)
+	}
+
+	networkUnit := team.Name + ".network"
+	units := []Unit{{Name: networkUnit, Data: networkFile(team, networkName, network)}}
+
+	for _, key := range slices.Sorted(maps.Keys(stack.Services)) {
+		service := stack.Services[key]
+		addr, ok := service.Networks[networkName]
+		if !ok {
+			return nil, fmt.Errorf("service %s is not on the team network, only jeopardy stacks can be rendered", key)
+		}
+
+		name := team.Name + "-" + key
+		image := service.Image
+		if service.Build != "" {
+			units = append(units, Unit{Name: name + ".build", Data: buildFile(team, key, name, service)})
+			image = name + ".build"
+		}
+
+		units = append(units, Unit{Name: name + ".container", Data: containerFile(team, key, service, image, networkUnit, addr)})
+	}
+
+	return units, nil
+}
+
+func networkFile(team model.Team, networkName string, network model.Network) []byte {
+	var u unitFile
+	u.section("Unit")
+	u.set("Description", "CTFManager network of team "+team.Name)
+	u.section("Network")
+	u.set("NetworkName", networkName)
+	u.set("Driver", network.Driver)
+	if network.EnableIPv6 {
+		u.set("IPv6", "true")
+	}
+	for _, config := range network.IPAM.Config {
+		u.set("Subnet", config.Subnet)
+		u.set("Gateway", config.Gateway)
+	}
+	return u.bytes()
+}
+
+func buildFile(team model.Team, key, name string, service model.Service) []byte {
+	var u unitFile
+	u.section("Unit")
+	u.set("Description", fmt.Sprintf("CTFManager image of %s for team %s", key, team.Name))
+	u.section("Build")
+	u.set("ImageTag", "localhost/"+strings.ToLower(name))
+	u.set("SetWorkingDirectory", service.Build)
+	return u.bytes()
+}
+
+func containerFile(team model.Team, key string, service model.Service, image, networkUnit string, addr model.IPAddr) []byte {
+	var u unitFile
+	u.section("Unit")
+	u.set("Description", fmt.Sprintf("CTFManager %s of team %s", key, team.Name))
+
+	u.section("Container")
+	u.set("ContainerName", service.ContainerName)
+	u.set("Image", image)
+	u.set("Network", networkUnit)
+	u.set("IP", addr.Ipv4Address)
+	if addr.Ipv6Address != "" {
+		u.set("IP6", addr.Ipv6Address)
+	}
+	for _, port := range service.Ports {
+		u.set("PublishPort", port)
+	}
+	for _, port := range service.Expose {
+		u.set("ExposeHostPort", port)
+	}
+	if service.EnvFile != "" {
+		u.set("EnvironmentFile", service.EnvFile)
+	}
+	for _, env := range service.Environment {
+		u.set("Environment", env)
+	}
+	for _, volume := range service.Volumes {
+		// Relative sources are relative to the team directory, like in compose
+		if rest, ok := strings.CutPrefix(volume, "./"); ok {
+			volume = filepath.Join(team.Path, rest)
+		}
+		u.set("Volume", volume)
+	}
+	for _, capability := range service.CapAdd {
+		u.set("AddCapability", capability)
+	}
+	for _, k := range slices.Sorted(maps.Keys(service.Sysctls)) {
+		u.set("Sysctl", k+"="+service.Sysctls[k])
+	}
+
+	// Hardening and resource caps
+	if service.ReadOnly {
+		u.set("ReadOnly", "true")
+	}
+	for _, tmpfs := range service.Tmpfs {
+		u.set("Tmpfs", tmpfs)
+	}
+	if slices.Contains(service.SecurityOpt, "no-new-privileges:true") {
+		u.set("NoNewPrivileges", "true")
+	}
+	if service.Deploy != nil {
+		var args []string
+		if limits := service.Deploy.Resources.Limits; limits != nil {
+			if limits.CPUs != "" {
+				args = append(args, "--cpus="+limits.CPUs)
+			}
+			if limits.Memory != "" {
+				args = append(args, "--memory="+limits.Memory)
+			}
+			if limits.Pids != 0 {
+				u.set("PidsLimit", fmt.Sprint(limits.Pids))
+			}
+		}
+		if reservations := service.Deploy.Resources.Reservations; reservations != nil && reservations.Memory != "" {
+			args = append(args, "--memory-reservation="+reservations.Memory)
+		}
+		if len(args) > 0 {
+			u.set("PodmanArgs", strings.Join(args, " "))
+		}
+	}
+
+	if hc := service.Healthcheck; hc != nil && len(hc.Test) == 2 && hc.Test[0] == "CMD-SHELL" {
+		u.set("HealthCmd", hc.Test[1])
+		u.set("HealthInterval", hc.Interval)
+		u.set("HealthTimeout", hc.Timeout)
+		u.set("HealthRetries", fmt.Sprint(hc.Retries))
+	}
+
+	u.section("Service")
+	u.set("Restart", restartPolicy(service.Restart))
+
+	// On-demand challenges are started by hand, not on boot
+	if !slices.Contains(service.Profiles, model.OnDemandProfile) {
+		u.section("Install")
+		u.set("WantedBy", "default.target")
+	}
+
+	return u.bytes()
+}
+
+// restartPolicy converts a compose restart policy into a systemd one
+func restartPolicy(restart string) string {
+	switch {
+	case restart == "" || restart == "no":
+		return "no"
+	case strings.HasPrefix(restart, "on-failure"):
+		return "on-failure"
+	default:
+		return "always"
+	}
+}
+
+// unitFile builds a systemd unit file
+type unitFile struct {
+	b strings.Builder
+}
+
+func (u *unitFile) section(name string) {
+	if u.b.Len() == 0 {
+		u.b.WriteString("# Generated by ctfmanager, do not edit\n")
+	}
+	fmt.Fprintf(&u.b, "\n[%s]\n", name)
+}
+
+func (u *unitFile) set(key, value string) {
+	if value == "" {
+		return
+	}
+	fmt.Fprintf(&u.b, "%s=%s\n", key, strings.ReplaceAll(value, "\n", " "))
+}
+
+func (u *unitFile) bytes() []byte {
+	return []byte(u.b.String())
+}
@@ -0,0 +1,101 @@
+package deploy
+
+import (
+	"context"
+	"maps"
+	"net/netip"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/Lolozendev/CTFManager/internal/config"
+	"github.com/Lolozendev/CTFManager/internal/ipam"
+	"github.com/Lolozendev/CTFManager/internal/model"
+)
+
+// Conflict is a host route or Docker network overlapping a network managed by CTFManager
+type Conflict struct {
+	Network string `json:"network"` // Managed network, "teams" or "game"
+	Subnet  string `json:"subnet"`  // Its CIDR
+	Source  string `json:"source"`  // "route" or "docker"
+	Name    string `json:"name"`    // Interface or Docker network overlapping it
+	Overlap string `json:"overlap"` // CIDR overlapping it
+}
+
+// Conflicts looks for host routes and Docker networks overlapping the team
+// subnets and the game network. The networks of the given teams are not
+// conflicts; neither are routes of Docker bridges, which are checked through
+// Docker itself. With a host inventory, the Docker networks of every host are
+// checked, named "<host>/<network>".
+func (d *Deployer) Conflicts(ctx context.Context, teams []model.Team) ([]Conflict, error) {
+	type managedNetwork struct {
+		label  string
+		subnet netip.Prefix
+	}
+	managed := []managedNetwork{{"teams", d.config.IPAM().Parent()}}
+	if ipv6, ok := d.config.IPAM().IPv6(); ok {
+		managed = append(managed, managedNetwork{"teams", ipv6})
+	}
+	own := make(map[string]bool)
+	for _, t := range teams {
+		own[strings.ToLower(filepath.Base(t.Path)+"_"+t.Name+"-Network")] = true
+	}
+	if game := d.generator.GameNetwork(); game != nil {
+		managed = append(managed, managedNetwork{"game", game.Plan.Parent()})
+		own[strings.ToLower(game.Name)] = true
+	}
+
+	var conflicts []Conflict
+	check := func(source, name string, network netip.Prefix) {
+		for _, m := range managed {
+			if m.subnet.Overlaps(network) {
+				conflicts = append(conflicts, Conflict{
+					Network: m.label,
+					Subnet:  m.subnet.String(),
+					Source:  source,
+					Name:    name,
+					Overlap: network.String(),
+				})
+			}
+		}
+	}
+
+	routes, err := ipam.HostRoutes()
+	if err != nil {
+		return nil, err
+	}
+	for _, route := range routes {
+		if route.Interface == "docker0" || strings.HasPrefix(route.Interface, "br-") {
+			continue
+		}
+		check("route", route.Interface, route.Network)
+	}
+
+	hosts := d.config.Hosts
+	if len(hosts) == 0 {
+		hosts = []config.HostConfig{{}} // The local daemon
+	}
+	for _, host := range hosts {
+		networks, err := d.docker.On(host.Endpoint).Subnets(ctx)
+		if err != nil {
+			d.logger.Warn("Docker networks not checked", "host", host.Name, "error", err)
+			continue
+		}
+		for _, name := range slices.Sorted(maps.Keys(networks)) {
+			if own[strings.ToLower(name)] {
+				continue
+			}
+			label := name
+			if host.Name != "" {
+				label = host.Name + "/" + name
+			}
+			for _, s := range networks[name] {
+				if network, err := netip.ParsePrefix(s); err == nil {
+					check("docker", label, network)
+				}
+			}
+		}
+	}
+
+	return conflicts, nil
+}
@@ -0,0 +1,106 @@
+// Package deploy generates and starts team stacks
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Lolozendev/CTFManager/internal/app/challenge"
+	"github.com/Lolozendev/CTFManager/internal/app/compose"
+	"github.com/Lolozendev/CTFManager/internal/app/dns"
+	"github.com/Lolozendev/CTFManager/internal/app/wireguard"
+	"github.com/Lolozendev/CTFManager/internal/config"
+	"github.com/Lolozendev/CTFManager/internal/docker"
+	"github.com/Lolozendev/CTFManager/internal/model"
+	"github.com/charmbracelet/log"
+)
+
+// ErrOutsideEvent is returned when deploying outside the event window
+var ErrOutsideEvent = errors.New("the event is not running")
+
+// Deployer handles team stack deployments
+type Deployer struct {
+	config     *config.Config
+	logger     *log.Logger
+	challenges *challenge.Manager
+	generator  *compose.Generator
+	dns        *dns.Generator
+	wireguard  *wireguard.Generator
+	docker     *docker.Client
+}
+
+// New creates a new deployer
+func New(cfg *config.Config, logger *log.Logger) *Deployer {
+	return &Deployer{
+		config:     cfg,
+		logger:     logger,
+		challenges: challenge.New(cfg, logger),
+		generator:  compose.New(cfg, logger),
+		dns:        dns.New(cfg, logger),
+		wireguard:  wireguard.New(cfg, logger),
+		docker:     docker.New(logger),
+	}
+}
+
+// Regenerate writes the compose file, DNS configuration and WireGuard templates
+// of a team with the challenges released so far
+func (d *Deployer) Regenerate(t model.Team) (string, error) {
+	challenges, err := d.challenges.ListReleased(time.Now())
+	if err != nil {
+		return "", fmt.Errorf("failed to list challenges: %w", err)
+	}
+
+	if _, err := d.dns.Write(t, challenges); err != nil {
+		return "", err
+	}
+	if err := d.wireguard.Write(t); err != nil {
+		return "", err
+	}
+
+	return d.generator.Write(t, challenges)
+}
+
+// Deploy regenerates the compose file of a team and starts its stack, or only
+// writes it with backends other than compose. Deploys are refused outside the
+// event window unless force is set.
+func (d *Deployer) Deploy(ctx context.Context, t model.Team, force bool) error {
+	if !force && !d.config.Event.Running(time.Now()) {
+		return ErrOutsideEvent
+	}
+
+	path, err := d.Regenerate(t)
+	if err != nil {
+		return err
+	}
+
+	if d.config.Backend != config.BackendCompose {
+		// Applied by the operator with the tooling of the backend
+		d.logger.Info("Team stack written", "team", t.Name, "path", path)
+		return nil
+	}
+
+	if game := d.generator.GameNetwork(); game != nil {
+		if err := d.docker.EnsureNetwork(ctx, game.Name, game.Subnet(), game.Gateway()); err != nil {
+			return err
+		}
+	}
+
+	if err := d.docker.On(d.config.GetHostEndpoint(t.Host)).Up(ctx, t.Path); err != nil {
+		return err
+	}
+
+	d.logger.Info("Team deployed", "team", t.Name)
+	return nil
+}
+
+// StartVPN starts the WireGuard endpoint of a team
+func (d *Deployer) StartVPN(ctx context.Context, t model.Team) error {
+	return d.docker.On(d.config.GetHostEndpoint(t.Host)).Up(ctx, t.Path, "wireguard")
+}
+
+// StopVPN stops the WireGuard endpoint of a team
+func (d *Deployer) StopVPN(ctx context.Context, t model.Team) error {
+	return d.docker.On(d.config.GetHostEndpoint(t.Host)).Stop(ctx, t.Path, "wireguard")
+}
@@ -0,0 +1,124 @@
+// Package dns generates the dnsmasq configuration of CTF teams
+package dns
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"text/template"
+
+	"github.com/Lolozendev/CTFManager/internal/config"
+	"github.com/Lolozendev/CTFManager/internal/model"
+	"github.com/charmbracelet/log"
+)
+
+// defaultTemplate is used when Config.Paths.DnsmasqTemplate does not exist
+const defaultTemplate = `# Generated by ctfmanager for team {{ .Team.Name }}, do not edit
+no-resolv
+no-hosts
+domain-needed
+bogus-priv
+{{ range .Records }}address=/{{ .Name }}/{{ .IP }}
+{{ if .IPv6 }}address=/{{ .Name }}/{{ .IPv6 }}
+{{ end }}{{ end }}`
+
+// Record is a name served by the team resolver
+type Record struct {
+	Name string
+	IP   string
+	IPv6 string // AAAA record, empty when IPv6 is disabled
+}
+
+// Data is the value the dnsmasq template is executed with
+type Data struct {
+	Team    model.Team
+	Records []Record
+}
+
+// Generator handles dnsmasq configuration generation
+type Generator struct {
+	config *config.Config
+	logger *log.Logger
+}
+
+// New creates a new dnsmasq configuration generator
+func New(cfg *config.Config, logger *log.Logger) *Generator {
+	return &Generator{
+		config: cfg,
+		logger: logger,
+	}
+}
+
+// Records returns the names served on the subnets of a team, one per challenge
+func Records(addr model.TeamAddressing, challenges []model.Challenge) []Record {
+	records := make([]Record, 0, len(challenges))
+	for _, ch := range challenges {
+		record := Record{Name: ch.Name, IP: addr.IPv4.Host(ch.NetworkID).String()}
+		if addr.IPv6 != nil {
+			record.IPv6 = addr.IPv6.Host(ch.NetworkID).String()
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// Generate renders the dnsmasq configuration of a team
+func (g *Generator) Generate(team model.Team, challenges []model.Challenge) (string, error) {
+	text := defaultTemplate
+	if path := g.config.Paths.DnsmasqTemplate; path != "" {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			text = string(data)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("failed to read dnsmasq template: %w", err)
+		}
+	}
+
+	tmpl, err := template.New("dnsmasq").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse dnsmasq template: %w", err)
+	}
+
+	records := Records(g.config.TeamAddressing(team.ID), challenges)
+	if g.config.Mode == config.ModeKingOfTheHill {
+		records = slices.DeleteFunc(records, func(r Record) bool { return r.Name == g.config.KingOfTheHill.Challenge })
+		records = append(records, Record{Name: g.config.KingOfTheHill.Challenge, IP: g.config.GameNetwork().HillIP()})
+	}
+	if g.config.Mode == config.ModeAttackDefense {
+		// The vulnbox lives on the game network, not at its team network address
+		records = slices.DeleteFunc(records, func(r Record) bool { return r.Name == g.config.AttackDefense.Vulnbox })
+		records = append(records, Record{
+			Name: "vulnbox",
+			IP:   g.config.GameNetwork().VulnboxIP(team.ID),
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, Data{Team: team, Records: records}); err != nil {
+		return "", fmt.Errorf("failed to render dnsmasq template: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+// Write generates the dnsmasq configuration of a team and writes it into the team directory
+func (g *Generator) Write(team model.Team, challenges []model.Challenge) (string, error) {
+	conf, err := g.Generate(team, challenges)
+	if err != nil {
+		return "", err
+	}
+
+	confPath := filepath.Join(team.Path, "dns", "dnsmasq.conf")
+	if err := os.MkdirAll(filepath.Dir(confPath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create dns directory: %w", err)
+	}
+	if err := os.WriteFile(confPath, []byte(conf), 0644); err != nil {
+		return "", fmt.Errorf("failed to write dnsmasq configuration: %w", err)
+	}
+
+	g.logger.Debug("DNS configuration written", "team", team.Name, "path", confPath, "records", len(challenges))
+	return confPath, nil
+}
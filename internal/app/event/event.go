@@ -0,0 +1,141 @@
+// Package event enforces the event timeline: challenge releases and the event window
+package event
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Lolozendev/CTFManager/internal/app/challenge"
+	"github.com/Lolozendev/CTFManager/internal/app/deploy"
+	"github.com/Lolozendev/CTFManager/internal/app/team"
+	"github.com/Lolozendev/CTFManager/internal/config"
+	"github.com/charmbracelet/log"
+)
+
+// pollInterval is how often the timeline is evaluated
+const pollInterval = 15 * time.Second
+
+// Timeline releases challenges and opens or closes team VPN endpoints as the event progresses
+type Timeline struct {
+	config     *config.Config
+	logger     *log.Logger
+	challenges *challenge.Manager
+	teams      *team.Manager
+	deployer   *deploy.Deployer
+
+	running  *bool  // event window state applied last, nil before the first evaluation
+	released string // released challenges deployed last
+}
+
+// New creates a new event timeline
+func New(cfg *config.Config, logger *log.Logger) *Timeline {
+	return &Timeline{
+		config:     cfg,
+		logger:     logger,
+		challenges: challenge.New(cfg, logger),
+		teams:      team.New(cfg, logger),
+		deployer:   deploy.New(cfg, logger),
+	}
+}
+
+// Run applies the event timeline until the context is cancelled
+func (t *Timeline) Run(ctx context.Context) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	t.logger.Info("Event timeline started",
+		"start", formatTime(t.config.Event.Start), "end", formatTime(t.config.Event.End))
+	for {
+		t.tick(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			t.logger.Info("Event timeline stopped")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick applies the event window and deploys newly released challenges
+func (t *Timeline) tick(ctx context.Context, now time.Time) {
+	running := t.config.Event.Running(now)
+
+	released, err := t.challenges.ListReleased(now)
+	if err != nil {
+		t.logger.Error("Failed to list challenges", "error", err)
+		return
+	}
+	names := make([]string, len(released))
+	for i, ch := range released {
+		names[i] = ch.Name
+	}
+	slices.Sort(names)
+	releasedKey := strings.Join(names, ",")
+
+	windowChanged := t.running == nil || *t.running != running
+	releaseChanged := releasedKey != t.released
+	if !windowChanged && !releaseChanged {
+		return
+	}
+
+	teams, err := t.teams.List()
+	if err != nil {
+		t.logger.Error("Failed to list teams", "error", err)
+		return
+	}
+
+	if windowChanged {
+		if running {
+			t.logger.Info("Event window open, starting team VPN endpoints")
+		} else {
+			t.logger.Info("Event window closed, stopping team VPN endpoints")
+		}
+	}
+	if releaseChanged && t.running != nil {
+		t.logger.Info("Released challenges changed, updating team stacks", "challenges", releasedKey)
+	}
+
+	failed := false
+	for _, tm := range teams {
+		if !tm.Enabled {
+			continue
+		}
+
+		if !running {
+			// Keep compose files up to date, but deploy nothing outside the event
+			if _, err := t.deployer.Regenerate(tm); err != nil {
+				t.logger.Error("Failed to regenerate team stack", "team", tm.Name, "error", err)
+				failed = true
+			}
+			if windowChanged {
+				if err := t.deployer.StopVPN(ctx, tm); err != nil {
+					t.logger.Error("Failed to stop VPN endpoint", "team", tm.Name, "error", err)
+					failed = true
+				}
+			}
+			continue
+		}
+
+		if err := t.deployer.Deploy(ctx, tm, false); err != nil {
+			t.logger.Error("Failed to deploy team stack", "team", tm.Name, "error", err)
+			failed = true
+		}
+	}
+
+	// Retry on the next tick when something failed
+	if failed {
+		return
+	}
+	t.running = &running
+	t.released = releasedKey
+}
+
+func formatTime(tm time.Time) string {
+	if tm.IsZero() {
+		return "unbounded"
+	}
+	return tm.Format(time.DateTime)
+}
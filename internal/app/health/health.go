@@ -0,0 +1,158 @@
+// Package health probes challenge instances to detect broken deployments
+package health
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Lolozendev/CTFManager/internal/config"
+	"github.com/Lolozendev/CTFManager/internal/model"
+	"github.com/charmbracelet/log"
+)
+
+// maxConcurrentProbes bounds the number of probes running at the same time
+const maxConcurrentProbes = 32
+
+// Result is the outcome of probing one challenge instance of one team
+type Result struct {
+	Team      string
+	Challenge string
+	Address   string
+	Err       error // nil when the instance is healthy
+}
+
+// Healthy reports whether the probe succeeded
+func (r Result) Healthy() bool {
+	return r.Err == nil
+}
+
+// Checker probes challenge instances over their team network addresses
+type Checker struct {
+	config *config.Config
+	logger *log.Logger
+}
+
+// New creates a new health checker
+func New(cfg *config.Config, logger *log.Logger) *Checker {
+	return &Checker{
+		config: cfg,
+		logger: logger,
+	}
+}
+
+// CheckAll probes every challenge declaring a healthcheck for every given team
+func (c *Checker) CheckAll(ctx context.Context, teams []model.Team, challenges []model.Challenge) []Result {
+	var (
+		results []Result
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		sem     = make(chan struct{}, maxConcurrentProbes)
+	)
+
+	for _, t := range teams {
+		for _, ch := range challenges {
+			if ch.Healthcheck == nil {
+				continue
+			}
+
+			wg.Add(1)
+			go func(t model.Team, ch model.Challenge) {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+
+				result := c.Check(ctx, t, ch)
+				if !result.Healthy() {
+					c.logger.Warn("Healthcheck failed", "team", t.Name, "challenge", ch.Name, "error", result.Err)
+				}
+
+				mu.Lock()
+				results = append(results, result)
+				mu.Unlock()
+			}(t, ch)
+		}
+	}
+
+	wg.Wait()
+	return results
+}
+
+// Check probes a single challenge instance of a team
+func (c *Checker) Check(ctx context.Context, t model.Team, ch model.Challenge) Result {
+	address := c.config.IPAM().Team(t.ID).Host(ch.NetworkID).String()
+	result := Result{
+		Team:      t.Name,
+		Challenge: ch.Name,
+		Address:   address,
+	}
+
+	hc := ch.Healthcheck
+	ctx, cancel := context.WithTimeout(ctx, hc.ProbeTimeout())
+	defer cancel()
+
+	switch hc.Type {
+	case model.HealthcheckTCP:
+		result.Err = probeTCP(ctx, address, hc.Port)
+	case model.HealthcheckHTTP:
+		result.Err = probeHTTP(ctx, address, *hc)
+	case model.HealthcheckExec:
+		result.Err = probeExec(ctx, t.Name+"-"+ch.Name, hc.Command)
+	default:
+		result.Err = fmt.Errorf("unknown healthcheck type %q", hc.Type)
+	}
+
+	return result
+}
+
+func probeTCP(ctx context.Context, address string, port int) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(address, strconv.Itoa(port)))
+	if err != nil {
+		return fmt.Errorf("tcp connect failed: %w", err)
+	}
+	return conn.Close()
+}
+
+func probeHTTP(ctx context.Context, address string, hc model.Healthcheck) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, hc.URL(address), nil)
+	if err != nil {
+		return fmt.Errorf("invalid http healthcheck: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("http request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != hc.ExpectedStatus() {
+		return fmt.Errorf("unexpected status %d (expected %d)", resp.StatusCode, hc.ExpectedStatus())
+	}
+
+	if hc.Body != "" {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if err != nil {
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+		if !strings.Contains(string(body), hc.Body) {
+			return fmt.Errorf("response body does not contain %q", hc.Body)
+		}
+	}
+
+	return nil
+}
+
+func probeExec(ctx context.Context, container string, command string) error {
+	out, err := exec.CommandContext(ctx, "docker", "exec", container, "sh", "-c", command).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("exec failed: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
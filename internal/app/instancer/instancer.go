@@ -0,0 +1,476 @@
+// Package instancer starts on-demand challenge instances for teams and reaps
+// them when they expire
+package instancer
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Lolozendev/CTFManager/internal/app/challenge"
+	"github.com/Lolozendev/CTFManager/internal/app/deploy"
+	"github.com/Lolozendev/CTFManager/internal/app/team"
+	"github.com/Lolozendev/CTFManager/internal/config"
+	"github.com/Lolozendev/CTFManager/internal/docker"
+	"github.com/Lolozendev/CTFManager/internal/model"
+	"github.com/charmbracelet/log"
+)
+
+const (
+	// FileName is the name of the running instances file in the data directory
+	FileName = "instances.json"
+
+	// KeyFileName is the name of the key team tokens are derived from
+	KeyFileName = "instancer.key"
+
+	// pendingTimeout is how long an instance may stay pending before it is
+	// considered abandoned, e.g. by a crash of the daemon while starting it
+	pendingTimeout = 10 * time.Minute
+)
+
+var (
+	// ErrUnknownChallenge is returned for challenges that cannot be started on demand
+	ErrUnknownChallenge = errors.New("no such on-demand challenge")
+
+	// ErrNoInstance is returned when a team has no instance of a challenge
+	ErrNoInstance = errors.New("no running instance")
+
+	// ErrLimit is returned when a team reached its concurrent instance limit
+	ErrLimit = errors.New("instance limit reached")
+
+	// ErrUnauthorized is returned for tokens matching no enabled team
+	ErrUnauthorized = errors.New("invalid token")
+)
+
+// Instance is a challenge started on demand for a team
+type Instance struct {
+	Team      string    `json:"team" yaml:"team"`
+	Challenge string    `json:"challenge" yaml:"challenge"`
+	Address   string    `json:"address" yaml:"address"`
+	Started   time.Time `json:"started" yaml:"started"`
+	Expires   time.Time `json:"expires" yaml:"expires"`
+	Pending   bool      `json:"pending,omitempty" yaml:"pending,omitempty"` // Still starting
+}
+
+// Instancer manages on-demand challenge instances
+type Instancer struct {
+	config     *config.Config
+	logger     *log.Logger
+	challenges *challenge.Manager
+	teams      *team.Manager
+	deployer   *deploy.Deployer
+	docker     *docker.Client
+
+	mu sync.Mutex // guards the instances file
+}
+
+// New creates a new instancer
+func New(cfg *config.Config, logger *log.Logger) *Instancer {
+	return &Instancer{
+		config:     cfg,
+		logger:     logger,
+		challenges: challenge.New(cfg, logger),
+		teams:      team.New(cfg, logger),
+		deployer:   deploy.New(cfg, logger),
+		docker:     docker.New(logger),
+	}
+}
+
+// Start starts an instance of an on-demand challenge for a team, or returns
+// the instance already running
+func (i *Instancer) Start(ctx context.Context, t model.Team, name string) (Instance, error) {
+	if !i.config.Event.Running(time.Now()) {
+		return Instance{}, deploy.ErrOutsideEvent
+	}
+
+	ch, err := i.challenge(name)
+	if err != nil {
+		return Instance{}, err
+	}
+
+	// Reserve the instance, then start it without holding the lock
+	i.mu.Lock()
+	instances, err := i.load()
+	if err != nil {
+		i.mu.Unlock()
+		return Instance{}, err
+	}
+	if idx := find(instances, t.Name, name); idx >= 0 {
+		i.mu.Unlock()
+		return instances[idx], nil
+	}
+
+	running := 0
+	for _, inst := range instances {
+		if inst.Team == t.Name {
+			running++
+		}
+	}
+	if running >= i.config.Instancer.MaxPerTeam {
+		i.mu.Unlock()
+		return Instance{}, fmt.Errorf("%w: team %s already runs %d instances", ErrLimit, t.Name, running)
+	}
+
+	now := time.Now()
+	instance := Instance{
+		Team:      t.Name,
+		Challenge: name,
+		Address:   i.config.IPAM().Team(t.ID).Host(ch.NetworkID).String(),
+		Started:   now,
+		Expires:   now.Add(i.config.Instancer.TTL),
+		Pending:   true,
+	}
+	err = i.save(append(instances, instance))
+	i.mu.Unlock()
+	if err != nil {
+		return Instance{}, err
+	}
+
+	// Regenerate first, the challenge may have been released after the last deploy
+	_, err = i.deployer.Regenerate(t)
+	if err == nil {
+		err = i.docker.On(i.config.GetHostEndpoint(t.Host)).RecreateService(ctx, t.Path, name)
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	instances, loadErr := i.load()
+	if loadErr != nil {
+		return Instance{}, loadErr
+	}
+	idx := find(instances, t.Name, name)
+	if err != nil {
+		if idx >= 0 {
+			instances = slices.Delete(instances, idx, idx+1)
+		}
+		if saveErr := i.save(instances); saveErr != nil {
+			i.logger.Warn("Failed to save instances", "error", saveErr)
+		}
+		return Instance{}, err
+	}
+	if idx < 0 {
+		return Instance{}, fmt.Errorf("%w: instance was stopped while starting", ErrNoInstance)
+	}
+
+	instances[idx].Pending = false
+	if err := i.save(instances); err != nil {
+		return Instance{}, err
+	}
+
+	i.logger.Info("Instance started", "team", t.Name, "challenge", name, "expires", instances[idx].Expires.Format(time.DateTime))
+	return instances[idx], nil
+}
+
+// Extend pushes back the expiry of an instance, within Instancer.MaxLifetime
+func (i *Instancer) Extend(t model.Team, name string) (Instance, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	instances, err := i.load()
+	if err != nil {
+		return Instance{}, err
+	}
+	idx := find(instances, t.Name, name)
+	if idx < 0 {
+		return Instance{}, fmt.Errorf("%w of %s", ErrNoInstance, name)
+	}
+
+	inst := &instances[idx]
+	expires := time.Now().Add(i.config.Instancer.Extension)
+	if limit := inst.Started.Add(i.config.Instancer.MaxLifetime); i.config.Instancer.MaxLifetime > 0 && expires.After(limit) {
+		expires = limit
+	}
+	if expires.After(inst.Expires) {
+		inst.Expires = expires
+	}
+
+	if err := i.save(instances); err != nil {
+		return Instance{}, err
+	}
+
+	i.logger.Info("Instance extended", "team", t.Name, "challenge", name, "expires", inst.Expires.Format(time.DateTime))
+	return *inst, nil
+}
+
+// Stop removes the instance of a challenge of a team
+func (i *Instancer) Stop(ctx context.Context, t model.Team, name string) error {
+	i.mu.Lock()
+	instances, err := i.load()
+	if err == nil && find(instances, t.Name, name) < 0 {
+		err = fmt.Errorf("%w of %s", ErrNoInstance, name)
+	}
+	i.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := i.docker.On(i.config.GetHostEndpoint(t.Host)).Remove(ctx, t.Path, name); err != nil {
+		return err
+	}
+
+	if err := i.forget(Instance{Team: t.Name, Challenge: name}); err != nil {
+		return err
+	}
+
+	i.logger.Info("Instance stopped", "team", t.Name, "challenge", name)
+	return nil
+}
+
+// forget removes an instance from the instances file
+func (i *Instancer) forget(inst Instance) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	instances, err := i.load()
+	if err != nil {
+		return err
+	}
+	if idx := find(instances, inst.Team, inst.Challenge); idx >= 0 {
+		instances = slices.Delete(instances, idx, idx+1)
+	}
+	return i.save(instances)
+}
+
+// List returns the running instances, of every team when teamName is empty
+func (i *Instancer) List(teamName string) ([]Instance, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	instances, err := i.load()
+	if err != nil {
+		return nil, err
+	}
+
+	if teamName != "" {
+		instances = slices.DeleteFunc(instances, func(inst Instance) bool { return inst.Team != teamName })
+	}
+	return instances, nil
+}
+
+// Reap stops the instances past their expiry, and the pending ones older than
+// pendingTimeout
+func (i *Instancer) Reap(ctx context.Context) {
+	instances, err := i.List("")
+	if err != nil {
+		i.logger.Error("Failed to list instances", "error", err)
+		return
+	}
+
+	now := time.Now()
+	for _, inst := range instances {
+		expired := !inst.Pending && !now.Before(inst.Expires)
+		abandoned := inst.Pending && now.Sub(inst.Started) > pendingTimeout
+		if !expired && !abandoned {
+			continue
+		}
+
+		if err := i.reap(ctx, inst); err != nil {
+			i.logger.Error("Failed to reap instance", "team", inst.Team, "challenge", inst.Challenge, "error", err)
+		}
+	}
+}
+
+// reap removes the container of an instance, then forgets the instance. The
+// team may have been disabled, renamed or deleted since the instance started.
+func (i *Instancer) reap(ctx context.Context, inst Instance) error {
+	t, err := i.team(inst.Team)
+	if err == nil {
+		err = i.docker.On(i.config.GetHostEndpoint(t.Host)).Remove(ctx, t.Path, inst.Challenge)
+	} else {
+		// Without a team directory, remove the container by name on every host
+		i.logger.Warn("Reaping instance of a missing team", "team", inst.Team, "challenge", inst.Challenge)
+		err = i.removeContainer(ctx, inst.Team+"-"+inst.Challenge)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := i.forget(inst); err != nil {
+		return err
+	}
+
+	i.logger.Info("Instance reaped", "team", inst.Team, "challenge", inst.Challenge, "pending", inst.Pending)
+	return nil
+}
+
+// removeContainer removes a container from every inventory host, or from the
+// local daemon without inventory
+func (i *Instancer) removeContainer(ctx context.Context, name string) error {
+	endpoints := []string{""}
+	if len(i.config.Hosts) > 0 {
+		endpoints = endpoints[:0]
+		for _, host := range i.config.Hosts {
+			endpoints = append(endpoints, host.Endpoint)
+		}
+	}
+
+	var errs []error
+	for _, endpoint := range endpoints {
+		if err := i.docker.On(endpoint).RemoveContainer(ctx, name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Token returns the API token of a team
+func (i *Instancer) Token(teamName string) (string, error) {
+	key, err := i.key()
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(teamName))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// Authenticate returns the enabled team a token belongs to
+func (i *Instancer) Authenticate(token string) (model.Team, error) {
+	teams, err := i.teams.List()
+	if err != nil {
+		return model.Team{}, err
+	}
+
+	for _, t := range teams {
+		if !t.Enabled {
+			continue
+		}
+		expected, err := i.Token(t.Name)
+		if err != nil {
+			return model.Team{}, err
+		}
+		if hmac.Equal([]byte(token), []byte(expected)) {
+			return t, nil
+		}
+	}
+
+	return model.Team{}, ErrUnauthorized
+}
+
+// challenge returns an enabled, released on-demand challenge
+func (i *Instancer) challenge(name string) (model.Challenge, error) {
+	challenges, err := i.challenges.ListReleased(time.Now())
+	if err != nil {
+		return model.Challenge{}, fmt.Errorf("failed to list challenges: %w", err)
+	}
+
+	for _, ch := range challenges {
+		if ch.Name == name && ch.OnDemand {
+			return ch, nil
+		}
+	}
+
+	return model.Challenge{}, fmt.Errorf("%w: %s", ErrUnknownChallenge, name)
+}
+
+// team returns a team by name, enabled or not
+func (i *Instancer) team(name string) (model.Team, error) {
+	teams, err := i.teams.List()
+	if err != nil {
+		return model.Team{}, err
+	}
+
+	for _, t := range teams {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+
+	return model.Team{}, fmt.Errorf("team %s not found", name)
+}
+
+// key returns the secret team tokens are derived from, creating it on first use
+func (i *Instancer) key() ([]byte, error) {
+	path := i.config.GetDataPath(KeyFileName)
+
+	data, err := os.ReadFile(path)
+	if err == nil {
+		return hex.DecodeString(string(data))
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read instancer key: %w", err)
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+	if err != nil {
+		if os.IsExist(err) {
+			return i.key()
+		}
+		return nil, fmt.Errorf("failed to create instancer key: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(hex.EncodeToString(key)); err != nil {
+		return nil, fmt.Errorf("failed to write instancer key: %w", err)
+	}
+	return key, nil
+}
+
+func (i *Instancer) load() ([]Instance, error) {
+	var instances []Instance
+
+	data, err := os.ReadFile(i.path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return instances, nil
+		}
+		return nil, fmt.Errorf("failed to read instances: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &instances); err != nil {
+		return nil, fmt.Errorf("invalid instances file: %w", err)
+	}
+
+	return instances, nil
+}
+
+func (i *Instancer) save(instances []Instance) error {
+	data, err := json.MarshalIndent(instances, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(i.path()), 0755); err != nil {
+		return err
+	}
+
+	tmp := i.path() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write instances: %w", err)
+	}
+	if err := os.Rename(tmp, i.path()); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write instances: %w", err)
+	}
+
+	return nil
+}
+
+func (i *Instancer) path() string {
+	return i.config.GetDataPath(FileName)
+}
+
+// find returns the index of the instance of a challenge of a team, or -1
+func find(instances []Instance, teamName string, name string) int {
+	return slices.IndexFunc(instances, func(inst Instance) bool {
+		return inst.Team == teamName && inst.Challenge == name
+	})
+}
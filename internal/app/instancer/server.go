@@ -0,0 +1,145 @@
+package instancer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Lolozendev/CTFManager/internal/app/deploy"
+	"github.com/Lolozendev/CTFManager/internal/model"
+)
+
+// reapInterval is how often expired instances are looked for
+const reapInterval = 15 * time.Second
+
+// Run serves the instancer HTTP API on Instancer.Listen and reaps expired
+// instances until the context is cancelled
+func (i *Instancer) Run(ctx context.Context) error {
+	server := &http.Server{
+		Addr:              i.config.Instancer.Listen,
+		Handler:           i.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServe()
+	}()
+
+	ticker := time.NewTicker(reapInterval)
+	defer ticker.Stop()
+
+	i.logger.Info("Instancer started", "listen", i.config.Instancer.Listen)
+	for {
+		select {
+		case err := <-errs:
+			return err
+		case <-ticker.C:
+			i.Reap(ctx)
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := server.Shutdown(shutdownCtx); err != nil {
+				return err
+			}
+			i.logger.Info("Instancer stopped")
+			return nil
+		}
+	}
+}
+
+// Handler returns the instancer HTTP API. Every request is authenticated with
+// the bearer token of a team and acts on that team's instances:
+//
+//	GET    /instances
+//	POST   /instances/{challenge}
+//	POST   /instances/{challenge}/extend
+//	DELETE /instances/{challenge}
+func (i *Instancer) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /instances", i.authenticated(func(w http.ResponseWriter, r *http.Request, t model.Team) {
+		instances, err := i.List(t.Name)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if instances == nil {
+			instances = []Instance{}
+		}
+		writeJSON(w, http.StatusOK, instances)
+	}))
+
+	mux.HandleFunc("POST /instances/{challenge}", i.authenticated(func(w http.ResponseWriter, r *http.Request, t model.Team) {
+		// A client going away must not leave a half-started instance
+		instance, err := i.Start(context.WithoutCancel(r.Context()), t, r.PathValue("challenge"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, instance)
+	}))
+
+	mux.HandleFunc("POST /instances/{challenge}/extend", i.authenticated(func(w http.ResponseWriter, r *http.Request, t model.Team) {
+		instance, err := i.Extend(t, r.PathValue("challenge"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, instance)
+	}))
+
+	mux.HandleFunc("DELETE /instances/{challenge}", i.authenticated(func(w http.ResponseWriter, r *http.Request, t model.Team) {
+		if err := i.Stop(r.Context(), t, r.PathValue("challenge")); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	return mux
+}
+
+// authenticated resolves the team of the request before calling the handler
+func (i *Instancer) authenticated(handler func(http.ResponseWriter, *http.Request, model.Team)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			writeError(w, ErrUnauthorized)
+			return
+		}
+
+		t, err := i.Authenticate(strings.TrimSpace(token))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		handler(w, r, t)
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrUnauthorized):
+		status = http.StatusUnauthorized
+	case errors.Is(err, ErrUnknownChallenge), errors.Is(err, ErrNoInstance):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrLimit):
+		status = http.StatusTooManyRequests
+	case errors.Is(err, deploy.ErrOutsideEvent):
+		status = http.StatusForbidden
+	}
+
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,77 @@
+package koth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// HistoryFileName is the name of the king-of-the-hill history file in the data directory
+const HistoryFileName = "koth.json"
+
+// Round is the outcome of one ownership poll
+type Round struct {
+	Tick   int       `json:"tick" yaml:"tick"`
+	Time   time.Time `json:"time" yaml:"time"`
+	Owner  string    `json:"owner,omitempty" yaml:"owner,omitempty"` // Team holding the hill, if any
+	Points int       `json:"points" yaml:"points"`
+	Error  string    `json:"error,omitempty" yaml:"error,omitempty"` // Why no team was awarded points
+}
+
+// History is every round played and the resulting scores
+type History struct {
+	Scores map[string]int `json:"scores"`
+	Rounds []Round        `json:"rounds"`
+}
+
+// Tick returns the number of rounds played
+func (h *History) Tick() int {
+	return len(h.Rounds)
+}
+
+// LoadHistory reads the history file, returning an empty history if it does not exist
+func LoadHistory(path string) (*History, error) {
+	history := &History{Scores: make(map[string]int)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return history, nil
+		}
+		return nil, fmt.Errorf("failed to read king-of-the-hill history: %w", err)
+	}
+
+	if err := json.Unmarshal(data, history); err != nil {
+		return nil, fmt.Errorf("invalid king-of-the-hill history: %w", err)
+	}
+	if history.Scores == nil {
+		history.Scores = make(map[string]int)
+	}
+
+	return history, nil
+}
+
+// saveHistory atomically writes the history file
+func saveHistory(path string, history *History) error {
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write king-of-the-hill history: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write king-of-the-hill history: %w", err)
+	}
+
+	return nil
+}
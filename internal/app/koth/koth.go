@@ -0,0 +1,217 @@
+// Package koth deploys the king-of-the-hill challenge and awards points to
+// the team holding it
+package koth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Lolozendev/CTFManager/internal/app/challenge"
+	"github.com/Lolozendev/CTFManager/internal/app/compose"
+	"github.com/Lolozendev/CTFManager/internal/app/deploy"
+	"github.com/Lolozendev/CTFManager/internal/app/team"
+	"github.com/Lolozendev/CTFManager/internal/config"
+	"github.com/Lolozendev/CTFManager/internal/docker"
+	"github.com/Lolozendev/CTFManager/internal/model"
+	"github.com/charmbracelet/log"
+)
+
+// ProjectDir is the data subdirectory holding the compose project of the hill
+const ProjectDir = "koth"
+
+// pollTimeout bounds the time spent reading the ownership of the hill
+const pollTimeout = 10 * time.Second
+
+// Hill handles the king-of-the-hill challenge
+type Hill struct {
+	config     *config.Config
+	logger     *log.Logger
+	challenges *challenge.Manager
+	teams      *team.Manager
+	generator  *compose.Generator
+	docker     *docker.Client
+}
+
+// New creates a new king-of-the-hill handler
+func New(cfg *config.Config, logger *log.Logger) *Hill {
+	return &Hill{
+		config:     cfg,
+		logger:     logger,
+		challenges: challenge.New(cfg, logger),
+		teams:      team.New(cfg, logger),
+		generator:  compose.New(cfg, logger),
+		docker:     docker.New(logger),
+	}
+}
+
+// Deploy generates the compose project of the hill and starts it. Deploys
+// are refused outside the event window unless force is set.
+func (h *Hill) Deploy(ctx context.Context, force bool) error {
+	if !force && !h.config.Event.Running(time.Now()) {
+		return deploy.ErrOutsideEvent
+	}
+
+	hill, err := h.challenge()
+	if err != nil {
+		return err
+	}
+
+	projectDir := h.config.GetDataPath(ProjectDir)
+	if _, err := h.generator.WriteHill(projectDir, hill); err != nil {
+		return err
+	}
+
+	game := h.config.GameNetwork()
+	if err := h.docker.EnsureNetwork(ctx, game.Name, game.Subnet(), game.Gateway()); err != nil {
+		return err
+	}
+	if err := h.docker.Up(ctx, projectDir); err != nil {
+		return err
+	}
+
+	h.logger.Info("Hill deployed", "challenge", hill.Name, "address", game.HillIP())
+	return nil
+}
+
+// challenge returns the contested challenge
+func (h *Hill) challenge() (model.Challenge, error) {
+	challenges, err := h.challenges.ListReleased(time.Now())
+	if err != nil {
+		return model.Challenge{}, fmt.Errorf("failed to list challenges: %w", err)
+	}
+
+	name := h.config.KingOfTheHill.Challenge
+	for _, ch := range challenges {
+		if ch.Name == name {
+			return ch, nil
+		}
+	}
+
+	return model.Challenge{}, fmt.Errorf("king-of-the-hill challenge %s is not enabled or not released yet", name)
+}
+
+// Run polls the ownership of the hill every tick while the event is running,
+// until the context is cancelled
+func (h *Hill) Run(ctx context.Context) error {
+	if h.config.KingOfTheHill.Tick <= 0 {
+		return fmt.Errorf("invalid king-of-the-hill tick %s", h.config.KingOfTheHill.Tick)
+	}
+	ticker := time.NewTicker(h.config.KingOfTheHill.Tick)
+	defer ticker.Stop()
+
+	h.logger.Info("Hill poller started", "challenge", h.config.KingOfTheHill.Challenge, "tick", h.config.KingOfTheHill.Tick)
+	for {
+		if h.config.Event.Running(time.Now()) {
+			if _, err := h.Poll(ctx); err != nil {
+				h.logger.Error("Hill poll failed", "error", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			h.logger.Info("Hill poller stopped")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll reads the owner of the hill, awards it the points of the tick and
+// records the round
+func (h *Hill) Poll(ctx context.Context) (Round, error) {
+	history, err := h.History()
+	if err != nil {
+		return Round{}, err
+	}
+
+	round := Round{Tick: history.Tick() + 1, Time: time.Now()}
+	owner, err := h.owner(ctx)
+	if err != nil {
+		round.Error = err.Error()
+		h.logger.Warn("No team holds the hill", "tick", round.Tick, "reason", err)
+	} else {
+		round.Owner = owner.Name
+		round.Points = h.config.KingOfTheHill.Points
+		history.Scores[owner.Name] += round.Points
+		h.logger.Info("Hill held", "tick", round.Tick, "team", owner.Name, "points", round.Points)
+	}
+
+	history.Rounds = append(history.Rounds, round)
+	return round, saveHistory(h.config.GetDataPath(HistoryFileName), history)
+}
+
+// History returns every round played so far and the scores
+func (h *Hill) History() (*History, error) {
+	return LoadHistory(h.config.GetDataPath(HistoryFileName))
+}
+
+// owner returns the enabled team whose ID is written in the ownership file or endpoint
+func (h *Hill) owner(ctx context.Context) (model.Team, error) {
+	ctx, cancel := context.WithTimeout(ctx, pollTimeout)
+	defer cancel()
+
+	var (
+		raw string
+		err error
+	)
+	ownership := h.config.KingOfTheHill.Ownership
+	if ownership.File != "" {
+		raw, err = h.docker.Exec(ctx, "koth-"+h.config.KingOfTheHill.Challenge, "cat", ownership.File)
+	} else {
+		raw, err = readEndpoint(ctx, h.config.GameNetwork().HillIP(), ownership.Port, ownership.Path)
+	}
+	if err != nil {
+		return model.Team{}, err
+	}
+
+	raw = strings.TrimSpace(raw)
+	id, err := strconv.Atoi(raw)
+	if err != nil {
+		return model.Team{}, fmt.Errorf("ownership %q is not a team ID", raw)
+	}
+
+	teams, err := h.teams.List()
+	if err != nil {
+		return model.Team{}, err
+	}
+	for _, t := range teams {
+		if t.Enabled && t.ID == id {
+			return t, nil
+		}
+	}
+
+	return model.Team{}, fmt.Errorf("no enabled team with ID %d", id)
+}
+
+// readEndpoint reads the ownership endpoint of the hill over HTTP
+func readEndpoint(ctx context.Context, address string, port int, path string) (string, error) {
+	url := "http://" + net.JoinHostPort(address, strconv.Itoa(port)) + "/" + strings.TrimPrefix(path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid ownership endpoint: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("ownership request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("ownership endpoint returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("failed to read ownership: %w", err)
+	}
+
+	return string(body), nil
+}
@@ -0,0 +1,303 @@
+// Package metrics exposes the state of the event to Prometheus
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Lolozendev/CTFManager/internal/app/challenge"
+	"github.com/Lolozendev/CTFManager/internal/app/team"
+	"github.com/Lolozendev/CTFManager/internal/audit"
+	"github.com/Lolozendev/CTFManager/internal/config"
+	"github.com/Lolozendev/CTFManager/internal/docker"
+	"github.com/Lolozendev/CTFManager/internal/model"
+	"github.com/charmbracelet/log"
+)
+
+// maxConcurrentExecs bounds the number of WireGuard containers queried at the same time
+const maxConcurrentExecs = 16
+
+// Docker is the part of the Docker client the exporter reads containers
+// through, on the daemon at an endpoint (empty for the local one)
+type Docker interface {
+	Running(ctx context.Context, endpoint string) (map[string]bool, error)
+	Exec(ctx context.Context, endpoint string, container string, command ...string) (string, error)
+}
+
+// hostClient drives the daemons of the host inventory with the docker CLI
+type hostClient struct {
+	client *docker.Client
+}
+
+func (h hostClient) Running(ctx context.Context, endpoint string) (map[string]bool, error) {
+	return h.client.On(endpoint).Running(ctx)
+}
+
+func (h hostClient) Exec(ctx context.Context, endpoint string, container string, command ...string) (string, error) {
+	return h.client.On(endpoint).Exec(ctx, container, command...)
+}
+
+// Exporter collects the metrics of the event on every scrape
+type Exporter struct {
+	config     *config.Config
+	logger     *log.Logger
+	challenges *challenge.Manager
+	teams      *team.Manager
+	audit      *audit.Log
+	docker     Docker
+}
+
+// New creates a new exporter reading containers with the docker CLI
+func New(cfg *config.Config, logger *log.Logger) *Exporter {
+	return NewWithDocker(cfg, logger, hostClient{client: docker.New(logger)})
+}
+
+// NewWithDocker creates a new exporter reading containers through a Docker client
+func NewWithDocker(cfg *config.Config, logger *log.Logger, client Docker) *Exporter {
+	return &Exporter{
+		config:     cfg,
+		logger:     logger,
+		challenges: challenge.New(cfg, logger),
+		teams:      team.New(cfg, logger),
+		audit:      audit.New(cfg.GetDataPath(audit.FileName)),
+		docker:     client,
+	}
+}
+
+// Collect returns the current metric families. Docker hosts that cannot be
+// reached are reported by ctfmanager_docker_up and leave out the samples of
+// their teams.
+func (e *Exporter) Collect(ctx context.Context) ([]Family, error) {
+	teams, err := e.teams.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list teams: %w", err)
+	}
+	challenges, err := e.challenges.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list challenges: %w", err)
+	}
+	entries, err := e.audit.Query(audit.Filter{})
+	if err != nil {
+		return nil, err
+	}
+
+	var enabledTeams []model.Team
+	for _, t := range teams {
+		if t.Enabled {
+			enabledTeams = append(enabledTeams, t)
+		}
+	}
+	var enabledChallenges []model.Challenge
+	for _, ch := range challenges {
+		if ch.Enabled {
+			enabledChallenges = append(enabledChallenges, ch)
+		}
+	}
+
+	families := []Family{
+		statusGauge("ctfmanager_teams", "Number of teams by status.", len(enabledTeams), len(teams)-len(enabledTeams)),
+		statusGauge("ctfmanager_challenges", "Number of challenges by status.", len(enabledChallenges), len(challenges)-len(enabledChallenges)),
+		adminOperations(entries),
+	}
+	families = append(families, e.containers(ctx, enabledTeams, enabledChallenges)...)
+
+	return families, nil
+}
+
+// statusGauge returns a gauge of enabled and disabled objects
+func statusGauge(name, help string, enabled, disabled int) Family {
+	return Family{
+		Name: name,
+		Help: help,
+		Type: TypeGauge,
+		Samples: []Sample{
+			{Labels: []Label{{"status", "enabled"}}, Value: float64(enabled)},
+			{Labels: []Label{{"status", "disabled"}}, Value: float64(disabled)},
+		},
+	}
+}
+
+// adminOperations counts the administrative actions of the audit log
+func adminOperations(entries []audit.Entry) Family {
+	type key struct{ action, result string }
+	counts := make(map[key]int)
+	for _, entry := range entries {
+		counts[key{entry.Action, entry.Result}]++
+	}
+
+	family := Family{
+		Name: "ctfmanager_admin_operations_total",
+		Help: "Number of administrative operations recorded in the audit log, by action and result.",
+		Type: TypeCounter,
+	}
+	keys := slices.SortedFunc(maps.Keys(counts), func(a, b key) int {
+		return strings.Compare(a.action+"\x00"+a.result, b.action+"\x00"+b.result)
+	})
+	for _, k := range keys {
+		family.Samples = append(family.Samples, Sample{
+			Labels: []Label{{"action", k.action}, {"result", k.result}},
+			Value:  float64(counts[k]),
+		})
+	}
+	return family
+}
+
+// containers returns the families read from Docker: the state of each
+// challenge container of each team and the handshake age of each VPN peer
+func (e *Exporter) containers(ctx context.Context, teams []model.Team, challenges []model.Challenge) []Family {
+	dockerUp := Family{
+		Name: "ctfmanager_docker_up",
+		Help: "Whether the Docker daemon of a host could be queried.",
+		Type: TypeGauge,
+	}
+	containerUp := Family{
+		Name: "ctfmanager_container_up",
+		Help: "Whether the container of a challenge of a team is running.",
+		Type: TypeGauge,
+	}
+
+	// Query each host once for the containers of all its teams
+	byHost := make(map[string][]model.Team)
+	for _, t := range teams {
+		byHost[t.Host] = append(byHost[t.Host], t)
+	}
+
+	var reachable []model.Team
+	for _, host := range slices.Sorted(maps.Keys(byHost)) {
+		running, err := e.docker.Running(ctx, e.config.GetHostEndpoint(host))
+		dockerUp.Samples = append(dockerUp.Samples, Sample{
+			Labels: []Label{{"host", hostLabel(host)}},
+			Value:  boolValue(err == nil),
+		})
+		if err != nil {
+			e.logger.Warn("Docker containers not collected", "host", hostLabel(host), "error", err)
+			continue
+		}
+
+		for _, t := range byHost[host] {
+			for _, ch := range challenges {
+				containerUp.Samples = append(containerUp.Samples, Sample{
+					Labels: []Label{{"team", t.Name}, {"challenge", ch.Name}},
+					Value:  boolValue(running[t.Name+"-"+ch.Name]),
+				})
+			}
+			if running[t.Name+"-wireguard"] {
+				reachable = append(reachable, t)
+			}
+		}
+	}
+	slices.SortFunc(containerUp.Samples, compareSamples)
+
+	return []Family{dockerUp, containerUp, e.handshakes(ctx, reachable)}
+}
+
+// handshakes returns the age of the latest handshake of every VPN peer of the
+// given teams. Peers that never completed a handshake have no sample.
+func (e *Exporter) handshakes(ctx context.Context, teams []model.Team) Family {
+	family := Family{
+		Name: "ctfmanager_wireguard_handshake_age_seconds",
+		Help: "Seconds since the latest WireGuard handshake of a team VPN peer.",
+		Type: TypeGauge,
+	}
+
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, maxConcurrentExecs)
+		now = time.Now().Unix() // Handshake times are in seconds
+	)
+	for _, t := range teams {
+		wg.Add(1)
+		go func(t model.Team) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			out, err := e.docker.Exec(ctx, e.config.GetHostEndpoint(t.Host), t.Name+"-wireguard",
+				"wg", "show", "all", "latest-handshakes")
+			if err != nil {
+				e.logger.Warn("WireGuard handshakes not collected", "team", t.Name, "error", err)
+				return
+			}
+
+			names := peerNames(t.Path)
+			var samples []Sample
+			for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+				// <interface> <public key> <unix time>
+				fields := strings.Fields(line)
+				if len(fields) != 3 {
+					continue
+				}
+				latest, err := strconv.ParseInt(fields[2], 10, 64)
+				if err != nil || latest == 0 {
+					continue
+				}
+
+				peer, ok := names[fields[1]]
+				if !ok {
+					peer = fields[1]
+				}
+				samples = append(samples, Sample{
+					Labels: []Label{{"team", t.Name}, {"peer", peer}},
+					Value:  float64(max(now-latest, 0)),
+				})
+			}
+
+			mu.Lock()
+			family.Samples = append(family.Samples, samples...)
+			mu.Unlock()
+		}(t)
+	}
+	wg.Wait()
+
+	slices.SortFunc(family.Samples, compareSamples)
+	return family
+}
+
+// peerNames maps the public keys of the VPN peers of a team directory to
+// their names, e.g. "peer1"
+func peerNames(teamPath string) map[string]string {
+	names := make(map[string]string)
+	keys, _ := filepath.Glob(filepath.Join(teamPath, "config", "peer*", "publickey-*"))
+	for _, path := range keys {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		names[strings.TrimSpace(string(data))] = filepath.Base(filepath.Dir(path))
+	}
+	return names
+}
+
+// hostLabel returns the name of an inventory host, "local" for the local daemon
+func hostLabel(host string) string {
+	if host == "" {
+		return "local"
+	}
+	return host
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// compareSamples orders samples by their label values
+func compareSamples(a, b Sample) int {
+	for i := range min(len(a.Labels), len(b.Labels)) {
+		if c := strings.Compare(a.Labels[i].Value, b.Labels[i].Value); c != 0 {
+			return c
+		}
+	}
+	return len(a.Labels) - len(b.Labels)
+}
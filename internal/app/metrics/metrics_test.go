@@ -0,0 +1,253 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Lolozendev/CTFManager/internal/audit"
+	"github.com/Lolozendev/CTFManager/internal/config"
+	"github.com/Lolozendev/CTFManager/internal/store"
+	"github.com/charmbracelet/log"
+)
+
+// fakeDocker serves containers and WireGuard handshakes by endpoint; endpoints
+// without containers fail like an unreachable daemon
+type fakeDocker struct {
+	running    map[string]map[string]bool
+	handshakes map[string]string // wg show output by container
+}
+
+func (f fakeDocker) Running(ctx context.Context, endpoint string) (map[string]bool, error) {
+	running, ok := f.running[endpoint]
+	if !ok {
+		return nil, errors.New("cannot connect to the Docker daemon")
+	}
+	return running, nil
+}
+
+func (f fakeDocker) Exec(ctx context.Context, endpoint string, container string, command ...string) (string, error) {
+	if strings.Join(command, " ") != "wg show all latest-handshakes" {
+		return "", fmt.Errorf("unexpected command %q", command)
+	}
+	return f.handshakes[container], nil
+}
+
+// newExporter sets up an event with teams alpha on node1, beta on the
+// unreachable node2 and gamma disabled, and challenges web and pwn enabled and
+// old disabled. Peer 1 of alpha connected 90 seconds ago, peer 2 never did.
+func newExporter(t *testing.T) *Exporter {
+	t.Helper()
+	dir := t.TempDir()
+
+	cfg := config.Default()
+	cfg.Paths.Data = filepath.Join(dir, "data")
+	cfg.Paths.Teams = filepath.Join(dir, "teams")
+	cfg.Paths.Challenges = filepath.Join(dir, "challenges")
+	cfg.Hosts = []config.HostConfig{
+		{Name: "node1", Endpoint: "ssh://ctf@node1", Address: "203.0.113.10"},
+		{Name: "node2", Endpoint: "ssh://ctf@node2", Address: "203.0.113.11"},
+	}
+
+	state := store.State{
+		Version: store.Version,
+		Teams: []store.TeamRecord{
+			{ID: 1, Name: "alpha", Enabled: true, Dir: "node1/alpha", Host: "node1"},
+			{ID: 2, Name: "beta", Enabled: true, Dir: "node2/beta", Host: "node2"},
+			{ID: 3, Name: "gamma", Enabled: false, Dir: "node1/gamma", Host: "node1"},
+		},
+		Challenges: []store.ChallengeRecord{
+			{Name: "web", NetworkID: 11, Enabled: true, Dir: "web"},
+			{Name: "pwn", NetworkID: 12, Enabled: true, Dir: "pwn"},
+			{Name: "old", NetworkID: 13, Enabled: false, Dir: "old"},
+		},
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, cfg.GetDataPath(store.FileName), string(data))
+	writeFile(t, filepath.Join(cfg.GetTeamPath("node1/alpha"), "config", "peer1", "publickey-peer1"), "KEY1\n")
+
+	auditLog := audit.New(cfg.GetDataPath(audit.FileName))
+	for _, entry := range []audit.Entry{
+		{Action: "team.create", Team: "alpha", Result: audit.ResultSuccess},
+		{Action: "team.create", Team: "beta", Result: audit.ResultSuccess},
+		{Action: "team.delete", Team: "delta", Result: audit.ResultFailure},
+	} {
+		if err := auditLog.Record(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	latest := time.Now().Unix() - 90
+	client := fakeDocker{
+		running: map[string]map[string]bool{
+			"ssh://ctf@node1": {"alpha-web": true, "alpha-pwn": false, "alpha-wireguard": true},
+		},
+		handshakes: map[string]string{
+			"alpha-wireguard": fmt.Sprintf("wg0\tKEY1\t%d\nwg0\tKEY2\t0\n", latest),
+		},
+	}
+	return NewWithDocker(cfg, log.New(io.Discard), client)
+}
+
+func writeFile(t *testing.T, path string, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// find returns the sample of a family with the given label values, in order
+func find(families []Family, name string, values ...string) (Sample, bool) {
+	for _, f := range families {
+		if f.Name != name {
+			continue
+		}
+		for _, s := range f.Samples {
+			if len(s.Labels) != len(values) {
+				continue
+			}
+			match := true
+			for i, l := range s.Labels {
+				match = match && l.Value == values[i]
+			}
+			if match {
+				return s, true
+			}
+		}
+	}
+	return Sample{}, false
+}
+
+func TestCollect(t *testing.T) {
+	families, err := newExporter(t).Collect(context.Background())
+	if err != nil {
+		t.Fatalf("Collect() failed: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		metric string
+		labels []string
+		want   float64
+	}{
+		{"enabled teams", "ctfmanager_teams", []string{"enabled"}, 2},
+		{"disabled teams", "ctfmanager_teams", []string{"disabled"}, 1},
+		{"disabled challenges", "ctfmanager_challenges", []string{"disabled"}, 1},
+		{"successful operations", "ctfmanager_admin_operations_total", []string{"team.create", "success"}, 2},
+		{"failed operations", "ctfmanager_admin_operations_total", []string{"team.delete", "failure"}, 1},
+		{"reachable host", "ctfmanager_docker_up", []string{"node1"}, 1},
+		{"unreachable host", "ctfmanager_docker_up", []string{"node2"}, 0},
+		{"running container", "ctfmanager_container_up", []string{"alpha", "web"}, 1},
+		{"stopped container", "ctfmanager_container_up", []string{"alpha", "pwn"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, ok := find(families, tt.metric, tt.labels...)
+			if !ok {
+				t.Fatalf("no %s sample for %v", tt.metric, tt.labels)
+			}
+			if s.Value != tt.want {
+				t.Errorf("%s%v = %v, want %v", tt.metric, tt.labels, s.Value, tt.want)
+			}
+		})
+	}
+
+	// Teams of an unreachable host and disabled teams have no container samples
+	if _, ok := find(families, "ctfmanager_container_up", "beta", "web"); ok {
+		t.Error("container sample for team beta on unreachable host node2")
+	}
+	if _, ok := find(families, "ctfmanager_container_up", "gamma", "web"); ok {
+		t.Error("container sample for disabled team gamma")
+	}
+	if _, ok := find(families, "ctfmanager_container_up", "alpha", "old"); ok {
+		t.Error("container sample for disabled challenge old")
+	}
+
+	// Handshake age, with peers named from their public key
+	s, ok := find(families, "ctfmanager_wireguard_handshake_age_seconds", "alpha", "peer1")
+	if !ok {
+		t.Fatal("no handshake sample for peer1 of alpha")
+	}
+	if s.Value < 90 || s.Value > 95 {
+		t.Errorf("handshake age of peer1 = %v, want about 90", s.Value)
+	}
+	if _, ok := find(families, "ctfmanager_wireguard_handshake_age_seconds", "alpha", "KEY2"); ok {
+		t.Error("handshake sample for a peer that never connected")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	server := httptest.NewServer(newExporter(t).Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /metrics = %d, want 200:\n%s", resp.StatusCode, body)
+	}
+	if got := resp.Header.Get("Content-Type"); got != ContentType {
+		t.Errorf("Content-Type = %q, want %q", got, ContentType)
+	}
+
+	for _, want := range []string{
+		"# HELP ctfmanager_teams Number of teams by status.\n# TYPE ctfmanager_teams gauge\n",
+		"ctfmanager_teams{status=\"enabled\"} 2\n",
+		"# TYPE ctfmanager_admin_operations_total counter\n",
+		"ctfmanager_admin_operations_total{action=\"team.create\",result=\"success\"} 2\n",
+		"ctfmanager_docker_up{host=\"node2\"} 0\n",
+		"ctfmanager_container_up{team=\"alpha\",challenge=\"pwn\"} 0\n",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("response lacks %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	families := []Family{{
+		Name: "test_metric",
+		Help: "Help with a \\ and a\nnewline.",
+		Type: TypeGauge,
+		Samples: []Sample{
+			{Labels: []Label{{"name", "quote\" backslash\\ newline\n"}, {"other", "x"}}, Value: 1.5},
+			{Value: 3},
+		},
+	}}
+
+	var buf strings.Builder
+	if err := Write(&buf, families); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# HELP test_metric Help with a \\ and a\nnewline.
+# TYPE test_metric gauge
+test_metric{name="quote\" backslash\\ newline\n",other="x"} 1.5
+test_metric 3
+`
+	if buf.String() != want {
+		t.Errorf("Write() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Run serves the metrics endpoint on Metrics.Listen until the context is cancelled
+func (e *Exporter) Run(ctx context.Context) error {
+	server := &http.Server{
+		Addr:              e.config.Metrics.Listen,
+		Handler:           e.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServe()
+	}()
+
+	e.logger.Info("Metrics endpoint started", "listen", e.config.Metrics.Listen)
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			return err
+		}
+		e.logger.Info("Metrics endpoint stopped")
+		return nil
+	}
+}
+
+// Handler returns the metrics HTTP endpoint:
+//
+//	GET /metrics
+func (e *Exporter) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		families, err := e.Collect(r.Context())
+		if err != nil {
+			e.logger.Error("Failed to collect metrics", "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		if err := Write(w, families); err != nil {
+			e.logger.Warn("Failed to write metrics", "error", err)
+		}
+	})
+
+	return mux
+}
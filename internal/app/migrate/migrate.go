@@ -0,0 +1,202 @@
+// Package migrate imports legacy directory layouts into the state store
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Lolozendev/CTFManager/internal/config"
+	"github.com/Lolozendev/CTFManager/internal/journal"
+	"github.com/Lolozendev/CTFManager/internal/model"
+	"github.com/Lolozendev/CTFManager/internal/store"
+	"github.com/charmbracelet/log"
+	"gopkg.in/yaml.v3"
+)
+
+// Report lists what a migration imported and skipped
+type Report struct {
+	Teams      []store.TeamRecord
+	Challenges []store.ChallengeRecord
+	Skipped    []string // Directories that could not be imported, with the reason
+}
+
+// Migrator imports directories named "<id>-<name>" / "x-<name>" into the state store
+type Migrator struct {
+	config *config.Config
+	logger *log.Logger
+	store  *store.Store
+}
+
+// New creates a new migrator
+func New(cfg *config.Config, logger *log.Logger) *Migrator {
+	return &Migrator{
+		config: cfg,
+		logger: logger,
+		store:  store.FromConfig(cfg),
+	}
+}
+
+// Run imports every team and challenge directory not already in the state store.
+// Directories are left untouched. With dryRun, the state is not saved.
+func (m *Migrator) Run(dryRun bool) (Report, error) {
+	var report Report
+
+	errDryRun := errors.New("dry run")
+	err := m.store.Update("migrate", func(state *store.State, op *journal.Op) error {
+		if err := m.importChallenges(state, &report); err != nil {
+			return err
+		}
+		if err := m.importTeams(state, &report); err != nil {
+			return err
+		}
+
+		// Abort the update to leave the state untouched
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return report, err
+	}
+
+	if !dryRun {
+		m.logger.Info("Migration complete",
+			"teams", len(report.Teams), "challenges", len(report.Challenges), "skipped", len(report.Skipped))
+	}
+	return report, nil
+}
+
+func (m *Migrator) importChallenges(state *store.State, report *Report) error {
+	entries, err := os.ReadDir(m.config.Paths.Challenges)
+	if err != nil {
+		return fmt.Errorf("failed to read challenges directory: %w", err)
+	}
+
+	registered := make(map[string]bool)
+	for _, ch := range state.Challenges {
+		registered[ch.Dir] = true
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || registered[entry.Name()] {
+			continue
+		}
+
+		networkID, name, enabled, err := model.ParseChallengeName(entry.Name())
+		if err == nil {
+			err = model.ValidateName(name)
+		}
+		if err != nil {
+			report.Skipped = append(report.Skipped, fmt.Sprintf("challenge %s: %v", entry.Name(), err))
+			continue
+		}
+
+		if reason := m.conflict(state.Challenge(name) != nil, enabled, networkID, func(id int) bool {
+			for _, ch := range state.Challenges {
+				if ch.Enabled && ch.NetworkID == id {
+					return true
+				}
+			}
+			return false
+		}); reason != "" {
+			report.Skipped = append(report.Skipped, fmt.Sprintf("challenge %s: %s", entry.Name(), reason))
+			continue
+		}
+
+		record := store.ChallengeRecord{
+			Name:      name,
+			NetworkID: networkID,
+			Enabled:   enabled,
+			Dir:       entry.Name(),
+		}
+		state.Challenges = append(state.Challenges, record)
+		report.Challenges = append(report.Challenges, record)
+	}
+
+	return nil
+}
+
+func (m *Migrator) importTeams(state *store.State, report *Report) error {
+	entries, err := os.ReadDir(m.config.Paths.Teams)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read teams directory: %w", err)
+	}
+
+	registered := make(map[string]bool)
+	for _, t := range state.Teams {
+		registered[t.Dir] = true
+	}
+
+	for _, entry := range entries {
+		// Host directories hold placed teams, which are already registered
+		if !entry.IsDir() || registered[entry.Name()] || m.config.Host(entry.Name()) != nil {
+			continue
+		}
+
+		id, name, enabled, err := model.ParseChallengeName(entry.Name())
+		if err == nil {
+			err = model.ValidateName(name)
+		}
+		if err != nil {
+			report.Skipped = append(report.Skipped, fmt.Sprintf("team %s: %v", entry.Name(), err))
+			continue
+		}
+
+		if reason := m.conflict(state.Team(name) != nil, enabled, id, func(id int) bool {
+			for _, t := range state.Teams {
+				if t.Enabled && t.ID == id {
+					return true
+				}
+			}
+			return false
+		}); reason != "" {
+			report.Skipped = append(report.Skipped, fmt.Sprintf("team %s: %s", entry.Name(), reason))
+			continue
+		}
+
+		record := store.TeamRecord{
+			ID:      id,
+			Name:    name,
+			Members: m.legacyMembers(entry.Name()),
+			Enabled: enabled,
+			Dir:     entry.Name(),
+		}
+		state.Teams = append(state.Teams, record)
+		report.Teams = append(report.Teams, record)
+	}
+
+	return nil
+}
+
+// conflict returns why an entry cannot be imported, or an empty string
+func (m *Migrator) conflict(nameTaken bool, enabled bool, id int, idTaken func(int) bool) string {
+	if nameTaken {
+		return "name already imported"
+	}
+	if enabled && idTaken(id) {
+		return fmt.Sprintf("ID %d already used", id)
+	}
+	return ""
+}
+
+// legacyMembers reads the members of a team from its team.yml file, if any
+func (m *Migrator) legacyMembers(dir string) []model.Member {
+	data, err := os.ReadFile(filepath.Join(m.config.GetTeamPath(dir), "team.yml"))
+	if err != nil {
+		return nil
+	}
+
+	var spec model.TeamSpec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		m.logger.Warn("Ignoring invalid team.yml", "dir", dir, "error", err)
+		return nil
+	}
+
+	return spec.Members
+}
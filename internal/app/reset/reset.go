@@ -0,0 +1,148 @@
+// Package reset recreates challenge containers of teams from a clean image
+package reset
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Lolozendev/CTFManager/internal/audit"
+	"github.com/Lolozendev/CTFManager/internal/config"
+	"github.com/Lolozendev/CTFManager/internal/docker"
+	"github.com/Lolozendev/CTFManager/internal/model"
+	"github.com/charmbracelet/log"
+)
+
+// RateLimitError is returned when an instance was reset too recently
+type RateLimitError struct {
+	Team      string
+	Challenge string
+	Remaining time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("challenge %s of team %s was reset recently, retry in %s",
+		e.Challenge, e.Team, e.Remaining.Round(time.Second))
+}
+
+// Resetter handles challenge reset operations
+type Resetter struct {
+	config *config.Config
+	logger *log.Logger
+	docker *docker.Client
+	audit  *audit.Log
+
+	mu sync.Mutex // guards the reset history file
+}
+
+// New creates a new challenge resetter
+func New(cfg *config.Config, logger *log.Logger) *Resetter {
+	return &Resetter{
+		config: cfg,
+		logger: logger,
+		docker: docker.New(logger),
+		audit:  audit.New(cfg.GetDataPath(audit.FileName)),
+	}
+}
+
+// Reset recreates the container of a challenge for a team. Unless force is
+// set, resets of the same instance are limited to one per Reset.MinInterval.
+// The command requesting it records the audit entry.
+func (r *Resetter) Reset(ctx context.Context, t model.Team, ch model.Challenge, force bool) error {
+	return r.reset(ctx, t, ch, force)
+}
+
+// ResetScheduled recreates the container of a challenge for a team as part of
+// its reset schedule, bypassing the rate limit, and records it in the audit log
+func (r *Resetter) ResetScheduled(ctx context.Context, t model.Team, ch model.Challenge) error {
+	err := r.reset(ctx, t, ch, true)
+
+	entry := audit.Entry{
+		Action:    "challenge.scheduled_reset",
+		Team:      t.Name,
+		Challenge: ch.Name,
+		Result:    audit.ResultSuccess,
+	}
+	if err != nil {
+		entry.Result = audit.ResultFailure
+		entry.Error = err.Error()
+	}
+	if auditErr := r.audit.Record(entry); auditErr != nil {
+		r.logger.Warn("Failed to record audit entry", "error", auditErr)
+	}
+
+	return err
+}
+
+func (r *Resetter) reset(ctx context.Context, t model.Team, ch model.Challenge, force bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	history, err := r.loadHistory()
+	if err != nil {
+		return err
+	}
+
+	key := t.Name + "/" + ch.Name
+	if last, ok := history[key]; ok && !force {
+		if elapsed := time.Since(last); elapsed < r.config.Reset.MinInterval {
+			return &RateLimitError{
+				Team:      t.Name,
+				Challenge: ch.Name,
+				Remaining: r.config.Reset.MinInterval - elapsed,
+			}
+		}
+	}
+
+	if err := r.docker.On(r.config.GetHostEndpoint(t.Host)).RecreateService(ctx, t.Path, ch.Name); err != nil {
+		return err
+	}
+
+	history[key] = time.Now()
+	if err := r.saveHistory(history); err != nil {
+		r.logger.Warn("Failed to save reset history", "error", err)
+	}
+
+	r.logger.Info("Challenge reset", "team", t.Name, "challenge", ch.Name)
+	return nil
+}
+
+// loadHistory reads the time of the last reset of each team instance
+func (r *Resetter) loadHistory() (map[string]time.Time, error) {
+	history := make(map[string]time.Time)
+
+	data, err := os.ReadFile(r.historyPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return history, nil
+		}
+		return nil, fmt.Errorf("failed to read reset history: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, fmt.Errorf("invalid reset history: %w", err)
+	}
+
+	return history, nil
+}
+
+func (r *Resetter) saveHistory(history map[string]time.Time) error {
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.historyPath()), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(r.historyPath(), data, 0644)
+}
+
+func (r *Resetter) historyPath() string {
+	return r.config.GetDataPath("resets.json")
+}
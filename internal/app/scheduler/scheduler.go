@@ -0,0 +1,148 @@
+// Package scheduler performs periodic challenge resets
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/Lolozendev/CTFManager/internal/app/challenge"
+	"github.com/Lolozendev/CTFManager/internal/app/reset"
+	"github.com/Lolozendev/CTFManager/internal/app/team"
+	"github.com/Lolozendev/CTFManager/internal/config"
+	"github.com/Lolozendev/CTFManager/internal/model"
+	"github.com/charmbracelet/log"
+)
+
+// pollInterval is how often the scheduler looks for due resets and reloads challenges
+const pollInterval = 15 * time.Second
+
+// job tracks the next activation of a challenge's reset schedule
+type job struct {
+	spec string
+	next time.Time
+}
+
+// Scheduler resets challenges for every team according to their reset schedule
+type Scheduler struct {
+	config     *config.Config
+	logger     *log.Logger
+	challenges *challenge.Manager
+	teams      *team.Manager
+	resetter   *reset.Resetter
+	jobs       map[string]*job
+}
+
+// New creates a new reset scheduler
+func New(cfg *config.Config, logger *log.Logger) *Scheduler {
+	return &Scheduler{
+		config:     cfg,
+		logger:     logger,
+		challenges: challenge.New(cfg, logger),
+		teams:      team.New(cfg, logger),
+		resetter:   reset.New(cfg, logger),
+		jobs:       make(map[string]*job),
+	}
+}
+
+// Run performs scheduled resets until the context is cancelled
+func (s *Scheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	s.logger.Info("Reset scheduler started")
+	for {
+		s.tick(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Reset scheduler stopped")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick reloads challenge schedules and runs the resets that are due
+func (s *Scheduler) tick(ctx context.Context, now time.Time) {
+	// Challenges not released yet have no service in team compose files
+	challenges, err := s.challenges.ListReleased(now)
+	if err != nil {
+		s.logger.Error("Failed to list challenges", "error", err)
+		return
+	}
+
+	active := make(map[string]bool)
+	for _, ch := range challenges {
+		// Resetting an on-demand challenge would start it for every team
+		if ch.Reset == nil || ch.OnDemand {
+			continue
+		}
+		active[ch.Name] = true
+
+		sched, err := ch.Reset.Schedule()
+		if err != nil {
+			s.logger.Warn("Invalid reset schedule", "challenge", ch.Name, "error", err)
+			continue
+		}
+
+		// (Re)compute the next activation for new or modified schedules
+		spec := ch.Reset.String()
+		j, ok := s.jobs[ch.Name]
+		if !ok || j.spec != spec {
+			j = &job{spec: spec, next: sched.Next(now)}
+			s.jobs[ch.Name] = j
+			s.logger.Info("Reset scheduled", "challenge", ch.Name, "schedule", spec, "next", j.next.Format(time.DateTime))
+		}
+
+		// A schedule without next activation never fires
+		if j.next.IsZero() || now.Before(j.next) {
+			continue
+		}
+
+		// Stacks are brought down at the end of the event, keep them down
+		if s.config.Event.Running(now) {
+			s.resetAll(ctx, ch)
+		} else {
+			s.logger.Info("Reset skipped outside the event", "challenge", ch.Name)
+		}
+		j.next = sched.Next(time.Now())
+		s.logger.Info("Next reset", "challenge", ch.Name, "next", j.next.Format(time.DateTime))
+	}
+
+	// Forget challenges that were disabled, are not released or lost their schedule
+	for name := range s.jobs {
+		if !active[name] {
+			delete(s.jobs, name)
+		}
+	}
+}
+
+// resetAll resets a challenge for every enabled team, spacing teams by Reset.Stagger
+func (s *Scheduler) resetAll(ctx context.Context, ch model.Challenge) {
+	teams, err := s.teams.List()
+	if err != nil {
+		s.logger.Error("Failed to list teams", "error", err)
+		return
+	}
+
+	s.logger.Info("Running scheduled reset", "challenge", ch.Name)
+	first := true
+	for _, t := range teams {
+		if !t.Enabled {
+			continue
+		}
+
+		if !first {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(s.config.Reset.Stagger):
+			}
+		}
+		first = false
+
+		if err := s.resetter.ResetScheduled(ctx, t, ch); err != nil {
+			s.logger.Error("Scheduled reset failed", "team", t.Name, "challenge", ch.Name, "error", err)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package team
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Lolozendev/CTFManager/internal/journal"
+	"github.com/Lolozendev/CTFManager/internal/store"
+)
+
+// Placement describes the assignment of a team to an inventory host
+type Placement struct {
+	Relocation
+	Previous string // Host the team was on, empty when it was not placed
+}
+
+// Place assigns every enabled team that is not on an inventory host to the
+// least loaded one, moving its directory under the directory of the host
+func (m *Manager) Place(ctx context.Context) ([]Placement, error) {
+	if len(m.config.Hosts) == 0 {
+		return nil, fmt.Errorf("no hosts in the inventory")
+	}
+
+	state, err := m.store.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	var placements []Placement
+	for _, record := range state.Teams {
+		if !record.Enabled || m.config.Host(record.Host) != nil {
+			continue
+		}
+
+		placement, err := m.PlaceOn(ctx, record.Name, "")
+		if err != nil {
+			return placements, err
+		}
+		placements = append(placements, placement)
+	}
+
+	return placements, nil
+}
+
+// PlaceOn assigns a team to an inventory host, or to the least loaded one when
+// host is empty. Peer configurations are rewritten for the host address.
+func (m *Manager) PlaceOn(ctx context.Context, name string, host string) (Placement, error) {
+	if host != "" && m.config.Host(host) == nil {
+		return Placement{}, fmt.Errorf("host %s not found in the inventory", host)
+	}
+
+	var target string
+	check := func(state *store.State, record *store.TeamRecord) error {
+		target = host
+		if target == "" {
+			target = m.pickHost(state)
+		}
+		if target == record.Host {
+			return fmt.Errorf("team %s is already on host %s", name, target)
+		}
+		newPath := m.config.GetTeamPath(filepath.Join(target, record.Name))
+		if _, err := os.Stat(newPath); !os.IsNotExist(err) {
+			return fmt.Errorf("team directory %s already exists", newPath)
+		}
+		return m.checkPeers(record)
+	}
+
+	var previous string
+	var rewritten int
+	result, err := m.relocate(ctx, "team.place", name, check, func(state *store.State, record *store.TeamRecord, op *journal.Op) error {
+		previous = record.Host
+		newDir := filepath.Join(target, record.Name)
+		newPath := m.config.GetTeamPath(newDir)
+		if err := os.MkdirAll(filepath.Dir(newPath), 0755); err != nil {
+			return fmt.Errorf("failed to create host directory: %w", err)
+		}
+		if err := op.Move(m.config.GetTeamPath(record.Dir), newPath); err != nil {
+			return err
+		}
+
+		var err error
+		rewritten, err = m.readdressVPN(newPath, record.ID, m.vpnAddress(target), op)
+		if err != nil {
+			return fmt.Errorf("failed to re-address VPN configuration: %w", err)
+		}
+
+		record.Host = target
+		record.Dir = newDir
+		return nil
+	})
+	result.VPNChanged = rewritten
+	if err != nil {
+		return Placement{Relocation: result, Previous: previous}, err
+	}
+
+	m.logger.Info("Team placed", "name", name, "host", result.Team.Host, "previous", previous)
+	return Placement{Relocation: result, Previous: previous}, nil
+}
+
+// pickHost returns the inventory host with the fewest enabled teams relative
+// to its weight, ties going to the first one, or empty without an inventory
+func (m *Manager) pickHost(state *store.State) string {
+	var best string
+	bestTeams, bestWeight := 0, 1
+	for _, host := range m.config.Hosts {
+		teams := 0
+		for _, t := range state.Teams {
+			if t.Enabled && t.Host == host.Name {
+				teams++
+			}
+		}
+		weight := max(host.Weight, 1)
+
+		// teams/weight < bestTeams/bestWeight, without rounding
+		if best == "" || teams*bestWeight < bestTeams*weight {
+			best, bestTeams, bestWeight = host.Name, teams, weight
+		}
+	}
+	return best
+}
@@ -2,156 +2,455 @@
 package team
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/Lolozendev/CTFManager/internal/app/backup"
+	"github.com/Lolozendev/CTFManager/internal/app/deploy"
 	"github.com/Lolozendev/CTFManager/internal/config"
+	"github.com/Lolozendev/CTFManager/internal/docker"
+	"github.com/Lolozendev/CTFManager/internal/journal"
 	"github.com/Lolozendev/CTFManager/internal/model"
+	"github.com/Lolozendev/CTFManager/internal/store"
 	"github.com/charmbracelet/log"
 )
 
 // Manager handles team operations
 type Manager struct {
-	config *config.Config
-	logger *log.Logger
+	config   *config.Config
+	logger   *log.Logger
+	store    *store.Store
+	docker   *docker.Client
+	deployer *deploy.Deployer
 }
 
 // New creates a new team manager
 func New(cfg *config.Config, logger *log.Logger) *Manager {
 	return &Manager{
-		config: cfg,
-		logger: logger,
+		config:   cfg,
+		logger:   logger,
+		store:    store.FromConfig(cfg),
+		docker:   docker.New(logger),
+		deployer: deploy.New(cfg, logger),
 	}
 }
 
 // Create creates a new team
-func (m *Manager) Create(id int, name string, members []string) error {
+func (m *Manager) Create(id int, name string, members []string) (model.Team, error) {
+	var team model.Team
+
 	// Validate team ID
 	if id < m.config.Teams.MinID || id > m.config.Teams.MaxID {
-		return fmt.Errorf("invalid team ID %d (must be between %d and %d)",
+		return team, fmt.Errorf("invalid team ID %d (must be between %d and %d)",
 			id, m.config.Teams.MinID, m.config.Teams.MaxID)
 	}
 
-	// Check if team already exists
-	teamPath := m.config.GetTeamPath(fmt.Sprintf("%d-%s", id, name))
-	if _, err := os.Stat(teamPath); !os.IsNotExist(err) {
-		return fmt.Errorf("team %s already exists", name)
+	if err := model.ValidateName(name); err != nil {
+		return team, err
 	}
 
-	// Create team directory
-	if err := os.MkdirAll(teamPath, 0755); err != nil {
-		return fmt.Errorf("failed to create team directory: %w", err)
-	}
+	err := m.store.Update("team.create", func(state *store.State, op *journal.Op) error {
+		// Check if team already exists, disabled teams included
+		if existing := state.Team(name); existing != nil {
+			if !existing.Enabled {
+				return fmt.Errorf("team %s already exists and is disabled, enable or delete it", name)
+			}
+			return fmt.Errorf("team %s already exists", name)
+		}
 
-	m.logger.Info("Team created", "id", id, "name", name, "members", len(members))
-	return nil
-}
+		for _, t := range state.Teams {
+			if t.Enabled && t.ID == id {
+				return fmt.Errorf("team ID %d is already used by team %s", id, t.Name)
+			}
+		}
 
-// List returns all teams
-func (m *Manager) List() ([]model.Team, error) {
-	entries, err := os.ReadDir(m.config.Paths.Teams)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read teams directory: %w", err)
-	}
+		// Spread teams across the host inventory, each host has its own directory
+		host := m.pickHost(state)
+		dir := filepath.Join(host, name)
 
-	var teams []model.Team
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
+		teamPath := m.config.GetTeamPath(dir)
+		if _, err := os.Stat(teamPath); !os.IsNotExist(err) {
+			return fmt.Errorf("team directory %s already exists", teamPath)
 		}
 
-		id, name, enabled, err := model.ParseChallengeName(entry.Name()) // Reuse same parsing logic
-		if err != nil {
-			m.logger.Warn("Skipping invalid team directory", "name", entry.Name(), "error", err)
-			continue
+		// Create team directory
+		if err := os.MkdirAll(teamPath, 0755); err != nil {
+			return fmt.Errorf("failed to create team directory: %w", err)
 		}
+		op.Create(teamPath)
 
-		team := model.Team{
+		record := store.TeamRecord{
 			ID:      id,
 			Name:    name,
-			Enabled: enabled,
+			Members: make([]model.Member, len(members)),
+			Enabled: true,
+			Dir:     dir,
+			Host:    host,
 		}
+		for i, username := range members {
+			record.Members[i] = model.Member{Username: username}
+		}
+
+		state.Teams = append(state.Teams, record)
+		team = m.toModel(record)
+		return nil
+	})
+	if err != nil {
+		return team, err
+	}
+
+	m.logger.Info("Team created", "id", id, "name", name, "members", len(members), "host", team.Host)
+	return team, nil
+}
+
+// List returns all teams
+func (m *Manager) List() ([]model.Team, error) {
+	state, err := m.store.Load()
+	if err != nil {
+		return nil, err
+	}
 
-		teams = append(teams, team)
+	teams := make([]model.Team, 0, len(state.Teams))
+	for _, record := range state.Teams {
+		teams = append(teams, m.toModel(record))
 	}
 
 	return teams, nil
 }
 
-// Delete removes a team
-func (m *Manager) Delete(name string) error {
+// toModel converts a persisted team into its model
+func (m *Manager) toModel(record store.TeamRecord) model.Team {
+	return model.Team{
+		ID:      record.ID,
+		Name:    record.Name,
+		Members: record.Members,
+		Enabled: record.Enabled,
+		Path:    m.config.GetTeamPath(record.Dir),
+		Host:    record.Host,
+	}
+}
+
+// DeleteOptions controls how a team is deleted
+type DeleteOptions struct {
+	KeepKeys bool // Archive the WireGuard configuration under the data directory
+}
+
+// Delete tears down the team's containers, networks and volumes, then removes
+// the team. It returns the path of the VPN key archive when KeepKeys is set.
+func (m *Manager) Delete(ctx context.Context, name string, opts DeleteOptions) (string, error) {
 	teams, err := m.List()
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	var found *model.Team
 	for _, t := range teams {
-		if t.Name == name && t.Enabled {
+		if t.Name == name {
 			found = &t
 			break
 		}
 	}
 
 	if found == nil {
-		return fmt.Errorf("team %s not found", name)
+		return "", fmt.Errorf("team %s not found", name)
 	}
 
-	teamPath := m.config.GetTeamPath(model.FormatChallengeName(found.ID, name, true))
-	if err := os.RemoveAll(teamPath); err != nil {
-		return fmt.Errorf("failed to delete team: %w", err)
+	// Release the team's subnet before its files disappear
+	if _, err := os.Stat(filepath.Join(found.Path, "compose.yml")); err == nil {
+		if err := m.docker.On(m.config.GetHostEndpoint(found.Host)).Down(ctx, found.Path); err != nil {
+			return "", err
+		}
+		m.logger.Info("Team stack removed", "name", name)
+	}
+
+	var keysArchive string
+	err = m.store.Update("team.delete", func(state *store.State, op *journal.Op) error {
+		record := state.Team(name)
+		if record == nil {
+			return fmt.Errorf("team %s not found", name)
+		}
+		teamPath := m.config.GetTeamPath(record.Dir)
+
+		if opts.KeepKeys {
+			keysDir := filepath.Join(teamPath, "config")
+			if _, err := os.Stat(keysDir); err == nil {
+				keysArchive = m.config.GetDataPath(filepath.Join("keys",
+					fmt.Sprintf("%s-%s.tar.gz", name, time.Now().Format("20060102-150405"))))
+				if err := backup.ArchiveDir(keysDir, keysArchive); err != nil {
+					return err
+				}
+				op.Create(keysArchive)
+			} else {
+				m.logger.Warn("Team has no VPN configuration to keep", "name", name)
+			}
+		}
+
+		// Keep the team directory in the journal trash, without its VPN keys
+		// unless they were asked for
+		if err := op.Trash(teamPath); err != nil {
+			return fmt.Errorf("failed to delete team: %w", err)
+		}
+		if !opts.KeepKeys {
+			trashed := op.Trashed[len(op.Trashed)-1].Trash
+			if err := os.RemoveAll(filepath.Join(trashed, "config")); err != nil {
+				return fmt.Errorf("failed to delete VPN keys: %w", err)
+			}
+		}
+
+		state.RemoveTeam(name)
+		return nil
+	})
+	if err != nil {
+		return "", err
 	}
 
 	m.logger.Info("Team deleted", "name", name)
-	return nil
+	return keysArchive, nil
 }
 
-// Disable disables a team
-func (m *Manager) Disable(name string) error {
-	teams, err := m.List()
+// Relocation describes the outcome of a team rename or move
+type Relocation struct {
+	Team       model.Team
+	Redeployed bool // The stack was running and has been started again
+	VPNChanged int  // Number of peer configurations rewritten; clients must download them again
+}
+
+// Rename gives a team a new name, moving its directory
+func (m *Manager) Rename(ctx context.Context, name string, newName string) (Relocation, error) {
+	if err := model.ValidateName(newName); err != nil {
+		return Relocation{}, err
+	}
+
+	check := func(state *store.State, record *store.TeamRecord) error {
+		if state.Team(newName) != nil {
+			return fmt.Errorf("team %s already exists", newName)
+		}
+		newPath := m.config.GetTeamPath(filepath.Join(record.Host, newName))
+		if _, err := os.Stat(newPath); !os.IsNotExist(err) {
+			return fmt.Errorf("team directory %s already exists", newPath)
+		}
+		return nil
+	}
+
+	return m.relocate(ctx, "team.rename", name, check, func(state *store.State, record *store.TeamRecord, op *journal.Op) error {
+		newDir := filepath.Join(record.Host, newName)
+		if err := op.Move(m.config.GetTeamPath(record.Dir), m.config.GetTeamPath(newDir)); err != nil {
+			return err
+		}
+
+		record.Name = newName
+		record.Dir = newDir
+		return nil
+	})
+}
+
+// Move gives a team a new ID, re-addressing its network and VPN configuration
+func (m *Manager) Move(ctx context.Context, name string, id int) (Relocation, error) {
+	if id < m.config.Teams.MinID || id > m.config.Teams.MaxID {
+		return Relocation{}, fmt.Errorf("invalid team ID %d (must be between %d and %d)",
+			id, m.config.Teams.MinID, m.config.Teams.MaxID)
+	}
+
+	check := func(state *store.State, record *store.TeamRecord) error {
+		if record.ID == id {
+			return fmt.Errorf("team %s already has ID %d", name, id)
+		}
+		for _, t := range state.Teams {
+			if t.Enabled && t.ID == id {
+				return fmt.Errorf("team ID %d is already used by team %s", id, t.Name)
+			}
+		}
+		return m.checkPeers(record)
+	}
+
+	var rewritten int
+	result, err := m.relocate(ctx, "team.move", name, check, func(state *store.State, record *store.TeamRecord, op *journal.Op) error {
+		var err error
+		rewritten, err = m.readdressVPN(m.config.GetTeamPath(record.Dir), id, m.vpnAddress(record.Host), op)
+		if err != nil {
+			return fmt.Errorf("failed to re-address VPN configuration: %w", err)
+		}
+
+		record.ID = id
+		return nil
+	})
+	result.VPNChanged = rewritten
+	return result, err
+}
+
+// relocate brings down the stack of a team, applies a change to its record,
+// then regenerates its compose file and DNS configuration. The stack is
+// started again if it was deployed. check validates the change before the
+// stack is brought down, and again under the lock before it is applied; if
+// the change still fails, the stack is started again as it was.
+func (m *Manager) relocate(ctx context.Context, action string, name string,
+	check func(*store.State, *store.TeamRecord) error,
+	change func(*store.State, *store.TeamRecord, *journal.Op) error) (Relocation, error) {
+	var result Relocation
+
+	state, err := m.store.Load()
 	if err != nil {
-		return err
+		return result, err
+	}
+	current := state.Team(name)
+	if current == nil || !current.Enabled {
+		return result, fmt.Errorf("team %s not found", name)
+	}
+	if err := check(state, current); err != nil {
+		return result, err
 	}
 
-	var found *model.Team
-	for _, t := range teams {
-		if t.Name == name && t.Enabled {
-			found = &t
-			break
+	// Container, network names and subnets derive from the name and ID
+	oldPath := m.config.GetTeamPath(current.Dir)
+	deployed := false
+	if _, err := os.Stat(filepath.Join(oldPath, "compose.yml")); err == nil {
+		if err := m.docker.On(m.config.GetHostEndpoint(current.Host)).Down(ctx, oldPath); err != nil {
+			return result, err
 		}
+		deployed = true
 	}
 
-	if found == nil {
-		return fmt.Errorf("enabled team %s not found", name)
+	var updated store.TeamRecord
+	err = m.store.Update(action, func(state *store.State, op *journal.Op) error {
+		record := state.Team(name)
+		if record == nil || !record.Enabled {
+			return fmt.Errorf("team %s not found", name)
+		}
+		if err := check(state, record); err != nil {
+			return err
+		}
+		if err := change(state, record, op); err != nil {
+			return err
+		}
+		updated = *record
+		return nil
+	})
+	if err != nil {
+		// The record and directory are unchanged, bring the stack back
+		if deployed {
+			if upErr := m.docker.On(m.config.GetHostEndpoint(current.Host)).Up(ctx, oldPath); upErr != nil {
+				m.logger.Error("Failed to restart team stack", "name", name, "error", upErr)
+			}
+		}
+		return result, err
+	}
+
+	result.Team = m.toModel(updated)
+	m.logger.Info("Team updated", "action", action, "name", result.Team.Name, "id", result.Team.ID)
+
+	if _, err := m.deployer.Regenerate(result.Team); err != nil {
+		return result, err
+	}
+	if deployed {
+		if err := m.docker.On(m.config.GetHostEndpoint(result.Team.Host)).Up(ctx, result.Team.Path); err != nil {
+			return result, err
+		}
+		result.Redeployed = true
 	}
 
-	oldPath := m.config.GetTeamPath(model.FormatChallengeName(found.ID, name, true))
-	newPath := m.config.GetTeamPath(model.FormatChallengeName(0, name, false))
+	return result, nil
+}
 
-	if err := os.Rename(oldPath, newPath); err != nil {
-		return fmt.Errorf("failed to disable team: %w", err)
+// Disable disables a team. Its ID, members and directory are kept so it can
+// be enabled again as it was.
+func (m *Manager) Disable(name string) error {
+	err := m.store.Update("team.disable", func(state *store.State, op *journal.Op) error {
+		found := state.Team(name)
+		if found == nil || !found.Enabled {
+			return fmt.Errorf("enabled team %s not found", name)
+		}
+
+		found.Enabled = false
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	m.logger.Info("Team disabled", "name", name)
 	return nil
 }
 
-// Enable enables a team
-func (m *Manager) Enable(name string, id int) error {
-	oldPath := m.config.GetTeamPath(fmt.Sprintf("x-%s", name))
-	if _, err := os.Stat(oldPath); os.IsNotExist(err) {
-		return fmt.Errorf("disabled team %s not found", name)
-	}
+// Enable enables a disabled team with its previous ID, or with id when it is
+// not zero. Peer configurations are re-addressed when the ID changes, and its
+// compose file and DNS configuration are regenerated; a deployed stack is
+// started again with the new addressing.
+func (m *Manager) Enable(ctx context.Context, name string, id int) (Relocation, error) {
+	var result Relocation
+	readdressed := false
+
+	err := m.store.Update("team.enable", func(state *store.State, op *journal.Op) error {
+		found := state.Team(name)
+		if found == nil || found.Enabled {
+			return fmt.Errorf("disabled team %s not found", name)
+		}
 
-	newPath := m.config.GetTeamPath(model.FormatChallengeName(id, name, true))
-	if err := os.Rename(oldPath, newPath); err != nil {
-		return fmt.Errorf("failed to enable team: %w", err)
+		if id == 0 {
+			if found.ID == 0 {
+				return fmt.Errorf("team %s has no previous ID, specify one", name)
+			}
+			id = found.ID
+		}
+		if id < m.config.Teams.MinID || id > m.config.Teams.MaxID {
+			return fmt.Errorf("invalid team ID %d (must be between %d and %d)",
+				id, m.config.Teams.MinID, m.config.Teams.MaxID)
+		}
+
+		for _, t := range state.Teams {
+			if t.Enabled && t.ID == id {
+				return fmt.Errorf("team ID %d is now used by team %s, enable %s with another ID", id, t.Name, name)
+			}
+		}
+
+		if found.ID != 0 && found.ID != id {
+			if err := m.checkPeers(found); err != nil {
+				return err
+			}
+			rewritten, err := m.readdressVPN(m.config.GetTeamPath(found.Dir), id, m.vpnAddress(found.Host), op)
+			if err != nil {
+				return fmt.Errorf("failed to re-address VPN configuration: %w", err)
+			}
+			result.VPNChanged = rewritten
+			readdressed = true
+		}
+
+		found.Enabled = true
+		found.ID = id
+		result.Team = m.toModel(*found)
+		return nil
+	})
+	if err != nil {
+		return result, err
 	}
 
 	m.logger.Info("Team enabled", "name", name, "id", id)
-	return nil
+
+	// A running stack still has the old addressing: bring it down with its old
+	// compose file before regenerating it
+	teamDocker := m.docker.On(m.config.GetHostEndpoint(result.Team.Host))
+	_, statErr := os.Stat(filepath.Join(result.Team.Path, "compose.yml"))
+	deployed := readdressed && statErr == nil
+	if deployed {
+		if err := teamDocker.Down(ctx, result.Team.Path); err != nil {
+			return result, err
+		}
+	}
+
+	if _, err := m.deployer.Regenerate(result.Team); err != nil {
+		return result, err
+	}
+	if deployed {
+		if err := teamDocker.Up(ctx, result.Team.Path); err != nil {
+			return result, err
+		}
+		result.Redeployed = true
+	}
+
+	return result, nil
 }
 
 // Validate checks if a team directory has the required structure
@@ -173,8 +472,7 @@ func (m *Manager) Validate(teamName string) error {
 		return fmt.Errorf("team %s not found", teamName)
 	}
 
-	teamPath := m.config.GetTeamPath(model.FormatChallengeName(found.ID, teamName, true))
-	composePath := filepath.Join(teamPath, "compose.yml")
+	composePath := filepath.Join(found.Path, "compose.yml")
 
 	if _, err := os.Stat(composePath); err != nil {
 		if errors.Is(err, os.ErrNotExist) {
@@ -0,0 +1,121 @@
+package team
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Lolozendev/CTFManager/internal/app/compose"
+	"github.com/Lolozendev/CTFManager/internal/journal"
+	"github.com/Lolozendev/CTFManager/internal/model"
+	"github.com/Lolozendev/CTFManager/internal/store"
+)
+
+// readdressVPN rewrites the peer configurations of a team directory for a new
+// team ID and, when not empty, a new endpoint address. Replaced files are kept
+// by the operation. It returns the number of rewritten files.
+func (m *Manager) readdressVPN(teamPath string, id int, address string, op *journal.Op) (int, error) {
+	peers, err := filepath.Glob(filepath.Join(teamPath, "config", "peer*", "*.conf"))
+	if err != nil {
+		return 0, err
+	}
+
+	// Peers route what the compose file of the team gives the WireGuard container
+	allowedIPs := model.VPNAllowedIPs(m.config.TeamAddressing(id), compose.New(m.config, m.logger).GameNetwork())
+
+	rewritten := 0
+	for _, path := range peers {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return rewritten, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		updated := m.readdressPeer(string(data), id, allowedIPs, address)
+		if updated == string(data) {
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return rewritten, err
+		}
+		if err := op.Trash(path); err != nil {
+			return rewritten, err
+		}
+		if err := os.WriteFile(path, []byte(updated), info.Mode().Perm()); err != nil {
+			return rewritten, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		op.Create(path)
+		rewritten++
+	}
+
+	return rewritten, nil
+}
+
+// checkPeers refuses to re-address a deployed team of an inventory host whose
+// peer configurations are not in its local directory: the WireGuard container
+// writes them on the host, and players would keep the stale ones
+func (m *Manager) checkPeers(record *store.TeamRecord) error {
+	teamPath := m.config.GetTeamPath(record.Dir)
+	if m.config.Host(record.Host) == nil || len(record.Members) == 0 {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(teamPath, "compose.yml")); err != nil {
+		return nil
+	}
+
+	peers, err := filepath.Glob(filepath.Join(teamPath, "config", "peer*", "*.conf"))
+	if err != nil {
+		return err
+	}
+	if len(peers) == 0 {
+		return fmt.Errorf("peer configurations of team %s are not in %s, copy them back from host %s first",
+			record.Name, filepath.Join(teamPath, "config"), record.Host)
+	}
+	return nil
+}
+
+// readdressPeer rewrites the DNS, AllowedIPs and Endpoint settings of a WireGuard peer configuration
+func (m *Manager) readdressPeer(conf string, id int, allowedIPs string, address string) string {
+	lines := strings.Split(conf, "\n")
+	for i, line := range lines {
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+
+		switch strings.TrimSpace(key) {
+		case "DNS":
+			value = m.config.IPAM().Team(id).DNS().String()
+		case "AllowedIPs":
+			value = allowedIPs
+		case "Endpoint":
+			host, _, err := net.SplitHostPort(strings.TrimSpace(value))
+			if err != nil {
+				continue
+			}
+			if address != "" {
+				host = address
+			}
+			value = net.JoinHostPort(host, strconv.Itoa(m.config.GetVPNPort(id)))
+		default:
+			continue
+		}
+
+		lines[i] = strings.TrimRight(key, " ") + " = " + value
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// vpnAddress returns the public VPN address of teams on an inventory host, or
+// empty for unplaced teams, whose peer configurations keep their endpoint
+func (m *Manager) vpnAddress(host string) string {
+	if m.config.Host(host) == nil {
+		return ""
+	}
+	return m.config.GetVPNAddress(host)
+}
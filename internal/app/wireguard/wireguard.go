@@ -0,0 +1,78 @@
+// Package wireguard generates the WireGuard configuration templates of CTF teams
+package wireguard
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Lolozendev/CTFManager/internal/config"
+	"github.com/Lolozendev/CTFManager/internal/model"
+	"github.com/charmbracelet/log"
+)
+
+// The templates are expanded by the shell of the linuxserver/wireguard image
+// when it generates configurations. They are its defaults, with a tunnel
+// address in model.VPNTunnel6 and IPv6 forwarded and masqueraded like IPv4.
+const (
+	serverTemplate = `[Interface]
+Address = ${INTERFACE}.1, ` + model.VPNTunnel6 + `1/64
+ListenPort = 51820
+PrivateKey = $(cat /config/server/privatekey-server)
+PostUp = iptables -A FORWARD -i %i -j ACCEPT; iptables -A FORWARD -o %i -j ACCEPT; iptables -t nat -A POSTROUTING -o eth+ -j MASQUERADE; ip6tables -A FORWARD -i %i -j ACCEPT; ip6tables -A FORWARD -o %i -j ACCEPT; ip6tables -t nat -A POSTROUTING -o eth+ -j MASQUERADE
+PostDown = iptables -D FORWARD -i %i -j ACCEPT; iptables -D FORWARD -o %i -j ACCEPT; iptables -t nat -D POSTROUTING -o eth+ -j MASQUERADE; ip6tables -D FORWARD -i %i -j ACCEPT; ip6tables -D FORWARD -o %i -j ACCEPT; ip6tables -t nat -D POSTROUTING -o eth+ -j MASQUERADE
+`
+
+	// ${CLIENT_IP##*.} is the last octet of the IPv4 address, see model.PeerTunnelIPv6
+	peerTemplate = `[Interface]
+Address = ${CLIENT_IP}, ` + model.VPNTunnel6 + `${CLIENT_IP##*.}/128
+PrivateKey = $(cat /config/${PEER_ID}/privatekey-${PEER_ID})
+ListenPort = 51820
+DNS = ${PEERDNS}
+
+[Peer]
+PublicKey = $(cat /config/server/publickey-server)
+PresharedKey = $(cat /config/${PEER_ID}/presharedkey-${PEER_ID})
+Endpoint = ${SERVERURL}:${SERVERPORT}
+AllowedIPs = ${ALLOWEDIPS}
+`
+)
+
+// Generator handles WireGuard template generation
+type Generator struct {
+	config *config.Config
+	logger *log.Logger
+}
+
+// New creates a new WireGuard template generator
+func New(cfg *config.Config, logger *log.Logger) *Generator {
+	return &Generator{
+		config: cfg,
+		logger: logger,
+	}
+}
+
+// Write writes the WireGuard templates of a team into the team directory when
+// IPv6 is enabled; the image's own templates are used otherwise
+func (g *Generator) Write(team model.Team) error {
+	if g.config.TeamAddressing(team.ID).IPv6 == nil {
+		return nil
+	}
+
+	files := map[string]string{
+		model.WireguardServerTemplate: serverTemplate,
+		model.WireguardPeerTemplate:   peerTemplate,
+	}
+	for name, data := range files {
+		path := filepath.Join(team.Path, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create wireguard directory: %w", err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			return fmt.Errorf("failed to write wireguard template: %w", err)
+		}
+	}
+
+	g.logger.Debug("WireGuard templates written", "team", team.Name)
+	return nil
+}
@@ -0,0 +1,161 @@
+// Package audit records administrative actions in an append-only log
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileName is the name of the audit log in the data directory
+const FileName = "audit.jsonl"
+
+// Results recorded in audit entries
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+// Entry is a single administrative action
+type Entry struct {
+	Time      time.Time       `json:"time"`
+	User      string          `json:"user"`
+	Command   string          `json:"command"`
+	Action    string          `json:"action"`
+	Team      string          `json:"team,omitempty"`
+	Challenge string          `json:"challenge,omitempty"`
+	Before    json.RawMessage `json:"before,omitempty"` // State of the affected object before the action
+	After     json.RawMessage `json:"after,omitempty"`  // State of the affected object after the action
+	Result    string          `json:"result"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// Filter selects audit entries. Zero fields match everything.
+type Filter struct {
+	Team      string
+	Challenge string
+	Action    string
+	Since     time.Time
+}
+
+// Match reports whether an entry is selected by the filter
+func (f Filter) Match(e Entry) bool {
+	if f.Team != "" && e.Team != f.Team {
+		return false
+	}
+	if f.Challenge != "" && e.Challenge != f.Challenge {
+		return false
+	}
+	if f.Action != "" && e.Action != f.Action && !strings.HasPrefix(e.Action, f.Action+".") {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	return true
+}
+
+// Log appends entries to a JSON lines file
+type Log struct {
+	path string
+}
+
+// New creates an audit log writing to the given file
+func New(path string) *Log {
+	return &Log{path: path}
+}
+
+// Record appends an entry to the log, filling in the time, user and command line
+func (l *Log) Record(entry Entry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	if entry.User == "" {
+		entry.User = CurrentUser()
+	}
+	if entry.Command == "" {
+		entry.Command = strings.Join(os.Args, " ")
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("failed to create audit directory: %w", err)
+	}
+
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+
+	return nil
+}
+
+// Query returns the entries matching the filter, oldest first
+func (l *Log) Query(filter Filter) ([]Entry, error) {
+	f, err := os.Open(l.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("invalid audit entry at line %d: %w", line, err)
+		}
+
+		if filter.Match(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	return entries, nil
+}
+
+// Snapshot encodes the state of an object for the Before and After fields.
+// A nil value, or one that cannot be encoded, yields no snapshot.
+func Snapshot(v any) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil || string(data) == "null" {
+		return nil
+	}
+	return data
+}
+
+// CurrentUser returns the OS user running the command, looking through sudo
+func CurrentUser() string {
+	if sudoUser := os.Getenv("SUDO_USER"); sudoUser != "" {
+		return sudoUser
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return "unknown"
+}
@@ -0,0 +1,86 @@
+// Package checker defines how attack-defense services are checked and how
+// flags are planted and retrieved each tick
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Status is the state of a team's service after a tick
+type Status string
+
+// Service statuses, from best to worst
+const (
+	StatusOK      Status = "ok"      // Service is functional and kept its flags
+	StatusCorrupt Status = "corrupt" // Service works but lost a flag
+	StatusMumble  Status = "mumble"  // Service answers incorrectly
+	StatusDown    Status = "down"    // Service is unreachable
+	StatusError   Status = "error"   // The checker itself failed
+)
+
+// Target is the service instance of a team being checked
+type Target struct {
+	Team    string
+	Service string
+	Address string
+	Port    int
+}
+
+// Flag is a secret planted in a service. ID lets the checker find it back.
+type Flag struct {
+	ID    string `json:"id"`
+	Value string `json:"value"`
+	Tick  int    `json:"tick"`
+}
+
+// Checker verifies a service and plants and retrieves flags in it. Methods
+// return nil when the service behaves, an *Error with the service status
+// otherwise, or any other error when the checker itself failed.
+type Checker interface {
+	Check(ctx context.Context, target Target) error
+	PutFlag(ctx context.Context, target Target, flag Flag) error
+	GetFlag(ctx context.Context, target Target, flag Flag) error
+}
+
+// Error reports a service that is not OK
+type Error struct {
+	Status  Status
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Status, e.Message)
+}
+
+// Down returns an error reporting an unreachable service
+func Down(format string, args ...any) error {
+	return &Error{Status: StatusDown, Message: fmt.Sprintf(format, args...)}
+}
+
+// Mumble returns an error reporting a service answering incorrectly
+func Mumble(format string, args ...any) error {
+	return &Error{Status: StatusMumble, Message: fmt.Sprintf(format, args...)}
+}
+
+// Corrupt returns an error reporting a service that lost a flag
+func Corrupt(format string, args ...any) error {
+	return &Error{Status: StatusCorrupt, Message: fmt.Sprintf(format, args...)}
+}
+
+// StatusOf returns the service status and message a checker error stands for
+func StatusOf(err error) (Status, string) {
+	if err == nil {
+		return StatusOK, ""
+	}
+
+	var checkErr *Error
+	if errors.As(err, &checkErr) {
+		return checkErr.Status, checkErr.Message
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return StatusDown, "checker timed out"
+	}
+	return StatusError, err.Error()
+}
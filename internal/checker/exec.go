@@ -0,0 +1,84 @@
+package checker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Exit codes of checker scripts
+const (
+	ExitOK      = 101
+	ExitCorrupt = 102
+	ExitMumble  = 103
+	ExitDown    = 104
+)
+
+// Exec adapts an external checker script. It is run as
+//
+//	<path> check <address> <port>
+//	<path> put <address> <port> <flag-id> <flag>
+//	<path> get <address> <port> <flag-id> <flag>
+//
+// and reports the service status with its exit code. The last line of its
+// output is used as the status message.
+type Exec struct {
+	Path string
+}
+
+// Check runs the check action of the script
+func (e Exec) Check(ctx context.Context, target Target) error {
+	return e.run(ctx, "check", target)
+}
+
+// PutFlag runs the put action of the script
+func (e Exec) PutFlag(ctx context.Context, target Target, flag Flag) error {
+	return e.run(ctx, "put", target, flag.ID, flag.Value)
+}
+
+// GetFlag runs the get action of the script
+func (e Exec) GetFlag(ctx context.Context, target Target, flag Flag) error {
+	return e.run(ctx, "get", target, flag.ID, flag.Value)
+}
+
+func (e Exec) run(ctx context.Context, action string, target Target, args ...string) error {
+	var out bytes.Buffer
+
+	args = append([]string{action, target.Address, strconv.Itoa(target.Port)}, args...)
+	cmd := exec.CommandContext(ctx, e.Path, args...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	message := lastLine(out.String())
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return fmt.Errorf("failed to run checker %s: %w", e.Path, err)
+	}
+
+	switch code := cmd.ProcessState.ExitCode(); code {
+	case ExitOK:
+		return nil
+	case ExitCorrupt:
+		return &Error{Status: StatusCorrupt, Message: message}
+	case ExitMumble:
+		return &Error{Status: StatusMumble, Message: message}
+	case ExitDown:
+		return &Error{Status: StatusDown, Message: message}
+	default:
+		return fmt.Errorf("checker %s %s exited with code %d: %s", e.Path, action, code, message)
+	}
+}
+
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
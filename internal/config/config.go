@@ -4,51 +4,238 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
+
+	"github.com/Lolozendev/CTFManager/internal/ipam"
+	"github.com/Lolozendev/CTFManager/internal/model"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPath is the configuration file loaded when none is specified
+const DefaultPath = "/etc/ctfmanager/config.yml"
+
+// Event modes
+const (
+	ModeJeopardy      = "jeopardy"         // Isolated per-team networks
+	ModeAttackDefense = "attack-defense"   // Per-team vulnboxes on a shared game network
+	ModeKingOfTheHill = "king-of-the-hill" // A single contested challenge on a shared game network
+)
+
+// Deployment backends
+const (
+	BackendCompose    = "compose"    // Docker Compose projects started by CTFManager
+	BackendKubernetes = "kubernetes" // Manifests applied with kubectl
+	BackendQuadlet    = "quadlet"    // Podman Quadlet units run by systemd
 )
 
 // Config holds all configuration for CTFManager
 type Config struct {
-	Paths      PathConfig
-	Network    NetworkConfig
-	Challenges ChallengeConfig
-	Teams      TeamConfig
+	Mode          string                 `yaml:"mode"`
+	Backend       string                 `yaml:"backend"`
+	Game          GameConfig             `yaml:"game"`
+	AttackDefense AttackDefenseConfig    `yaml:"attack_defense"`
+	KingOfTheHill KingOfTheHillConfig    `yaml:"king_of_the_hill"`
+	Instancer     InstancerConfig        `yaml:"instancer"`
+	Metrics       MetricsConfig          `yaml:"metrics"`
+	Kubernetes    KubernetesConfig       `yaml:"kubernetes"`
+	Hosts         []HostConfig           `yaml:"hosts"`       // Docker hosts teams are spread across; empty runs every team locally
+	VPNAddress    string                 `yaml:"vpn_address"` // Public address of the VPN endpoints of teams not on an inventory host
+	Paths         PathConfig             `yaml:"paths"`
+	Network       NetworkConfig          `yaml:"network"`
+	Challenges    ChallengeConfig        `yaml:"challenges"`
+	Teams         TeamConfig             `yaml:"teams"`
+	Containers    model.ContainerOptions `yaml:"containers"` // Defaults applied to every challenge container
+	Reset         ResetConfig            `yaml:"reset"`
+	Event         EventConfig            `yaml:"event"`
+	Lock          LockConfig             `yaml:"lock"`
+	Journal       JournalConfig          `yaml:"journal"`
+
+	plan     *ipam.Plan // Team subnets, resolved from Network
+	gamePlan *ipam.Plan // Game network, resolved from Game
 }
 
 // PathConfig defines file system paths
 type PathConfig struct {
-	Challenges      string
-	Teams           string
-	DnsmasqTemplate string
+	Challenges      string `yaml:"challenges"`
+	Teams           string `yaml:"teams"`
+	DnsmasqTemplate string `yaml:"dnsmasq_template"`
+	Data            string `yaml:"data"` // CTFManager state (audit log, reset history, ...)
 }
 
-// NetworkConfig defines network ranges
+// NetworkConfig defines how team subnets are carved out of a parent network
 type NetworkConfig struct {
-	BaseSubnet string // e.g., "10.0"
+	Parent     string `yaml:"parent"`      // e.g., "10.0.0.0/16"
+	TeamPrefix int    `yaml:"team_prefix"` // e.g., 24, team X is then 10.0.X.0/24
+	IPv6       string `yaml:"ipv6"`        // Optional ULA prefix, e.g., "fd00:c7f::/48", team X is then fd00:c7f:0:X::/64
+}
+
+// GameConfig defines the network shared by every team in attack-defense and
+// king-of-the-hill modes
+type GameConfig struct {
+	Network    string `yaml:"network"`     // Docker network shared by every team
+	Subnet     string `yaml:"subnet"`      // e.g., "10.60.0.0/16"
+	TeamPrefix int    `yaml:"team_prefix"` // e.g., 24, vulnbox of team X is then 10.60.X.1
+}
+
+// AttackDefenseConfig defines the vulnbox and service checks of attack-defense events
+type AttackDefenseConfig struct {
+	Vulnbox string `yaml:"vulnbox"` // Challenge deployed as every team's vulnbox
+
+	Tick           time.Duration   `yaml:"tick"`            // Interval between two rounds of checks
+	CheckerTimeout time.Duration   `yaml:"checker_timeout"` // Maximum duration of a checker action
+	FlagLifetime   int             `yaml:"flag_lifetime"`   // Number of ticks a flag must be retrievable for
+	Services       []ServiceConfig `yaml:"services"`        // Services of the vulnbox
+}
+
+// ServiceConfig defines a service of the vulnbox and its checker
+type ServiceConfig struct {
+	Name    string `yaml:"name"`
+	Port    int    `yaml:"port"`
+	Checker string `yaml:"checker"` // Path to the checker script
+}
+
+// KingOfTheHillConfig defines the contested challenge of king-of-the-hill events
+type KingOfTheHillConfig struct {
+	Challenge string          `yaml:"challenge"` // Challenge deployed once on the game network
+	Tick      time.Duration   `yaml:"tick"`      // Interval between two ownership polls
+	Points    int             `yaml:"points"`    // Points awarded to the owner each tick
+	Ownership OwnershipConfig `yaml:"ownership"`
+}
+
+// OwnershipConfig defines where the ID of the team owning the hill is read
+type OwnershipConfig struct {
+	File string `yaml:"file"` // Path of the ownership file in the challenge container
+	Port int    `yaml:"port"` // Or, HTTP port of the ownership endpoint
+	Path string `yaml:"path"` // and its path
+}
+
+// InstancerConfig defines how teams start on-demand challenge instances
+type InstancerConfig struct {
+	Listen      string        `yaml:"listen"`       // HTTP API address, e.g. ":8081"; empty disables the instancer
+	TTL         time.Duration `yaml:"ttl"`          // Lifetime of a new instance
+	Extension   time.Duration `yaml:"extension"`    // Lifetime added by an extension
+	MaxLifetime time.Duration `yaml:"max_lifetime"` // Instances are reaped past this age, even if extended
+	MaxPerTeam  int           `yaml:"max_per_team"` // Concurrent instances per team
+}
+
+// MetricsConfig defines the Prometheus endpoint served by the daemon
+type MetricsConfig struct {
+	Listen string `yaml:"listen"` // HTTP address of /metrics, e.g. ":9100"; empty disables metrics
+}
+
+// HostConfig defines a Docker host of the inventory
+type HostConfig struct {
+	Name     string `yaml:"name"`
+	Endpoint string `yaml:"endpoint"` // Docker daemon, e.g. "ssh://ctf@node1" or "tcp://10.1.0.2:2376"
+	Address  string `yaml:"address"`  // Public address of the VPN endpoints of its teams
+	Weight   int    `yaml:"weight"`   // Share of the teams relative to other hosts (default 1)
+}
+
+// Host returns the inventory host with the given name, or nil
+func (c *Config) Host(name string) *HostConfig {
+	for i := range c.Hosts {
+		if c.Hosts[i].Name == name {
+			return &c.Hosts[i]
+		}
+	}
+	return nil
+}
+
+// KubernetesConfig defines the manifests rendered by the kubernetes backend
+type KubernetesConfig struct {
+	Registry string `yaml:"registry"` // Challenge images are <registry>/<challenge>, pushed beforehand
 }
 
 // ChallengeConfig defines challenge constraints
 type ChallengeConfig struct {
-	MinNetworkID int
-	MaxNetworkID int
+	MinNetworkID int `yaml:"min_network_id"`
+	MaxNetworkID int `yaml:"max_network_id"`
 }
 
 // TeamConfig defines team constraints
 type TeamConfig struct {
-	MinID       int
-	MaxID       int
-	BaseVPNPort int // Base port for VPN (e.g., 50000)
+	MinID       int `yaml:"min_id"`
+	MaxID       int `yaml:"max_id"`
+	BaseVPNPort int `yaml:"base_vpn_port"` // Base port for VPN (e.g., 50000)
+}
+
+// ResetConfig defines challenge reset constraints
+type ResetConfig struct {
+	MinInterval time.Duration `yaml:"min_interval"` // Minimum delay between two resets of the same team instance
+	Stagger     time.Duration `yaml:"stagger"`      // Delay between teams during a scheduled reset
+}
+
+// LockConfig defines how concurrent invocations are serialized
+type LockConfig struct {
+	Timeout time.Duration `yaml:"timeout"` // Maximum wait for the data directory lock
+}
+
+// JournalConfig defines how many operations can be undone
+type JournalConfig struct {
+	Retention int `yaml:"retention"` // Number of operations kept, with what they removed
+}
+
+// EventConfig defines the event timeline. Zero times are unbounded.
+type EventConfig struct {
+	Start  time.Time `yaml:"start"`
+	End    time.Time `yaml:"end"`
+	Freeze time.Time `yaml:"freeze"` // Scoreboard freeze
+}
+
+// Running reports whether the event window is open at the given time
+func (e EventConfig) Running(now time.Time) bool {
+	if !e.Start.IsZero() && now.Before(e.Start) {
+		return false
+	}
+	if !e.End.IsZero() && !now.Before(e.End) {
+		return false
+	}
+	return true
+}
+
+// Frozen reports whether the scoreboard is frozen at the given time
+func (e EventConfig) Frozen(now time.Time) bool {
+	return !e.Freeze.IsZero() && !now.Before(e.Freeze)
 }
 
 // Default returns the default configuration
 func Default() *Config {
-	return &Config{
+	cfg := &Config{
+		Mode:       ModeJeopardy,
+		Backend:    BackendCompose,
+		VPNAddress: "127.0.0.1",
+		Game: GameConfig{
+			Network:    "ctfmanager-game",
+			Subnet:     "10.60.0.0/16",
+			TeamPrefix: 24,
+		},
+		AttackDefense: AttackDefenseConfig{
+			Tick:           time.Minute,
+			CheckerTimeout: 10 * time.Second,
+			FlagLifetime:   5,
+		},
+		KingOfTheHill: KingOfTheHillConfig{
+			Tick:   time.Minute,
+			Points: 1,
+			Ownership: OwnershipConfig{
+				File: "/king.txt",
+			},
+		},
+		Instancer: InstancerConfig{
+			TTL:         30 * time.Minute,
+			Extension:   30 * time.Minute,
+			MaxLifetime: 2 * time.Hour,
+			MaxPerTeam:  3,
+		},
 		Paths: PathConfig{
 			Challenges:      "/challenges",
 			Teams:           "/equipes",
 			DnsmasqTemplate: "/dnsconf/dnsmasq.template",
+			Data:            "/var/lib/ctfmanager",
 		},
 		Network: NetworkConfig{
-			BaseSubnet: "10.0",
+			Parent:     "10.0.0.0/16",
+			TeamPrefix: 24,
 		},
 		Challenges: ChallengeConfig{
 			MinNetworkID: 11,
@@ -59,6 +246,96 @@ func Default() *Config {
 			MaxID:       254,
 			BaseVPNPort: 50000,
 		},
+		Containers: model.ContainerOptions{
+			CPUs:        "1",
+			Memory:      "512m",
+			Pids:        256,
+			Restart:     "unless-stopped",
+			SecurityOpt: []string{"no-new-privileges:true"},
+		},
+		Reset: ResetConfig{
+			MinInterval: 5 * time.Minute,
+			Stagger:     5 * time.Second,
+		},
+		Lock: LockConfig{
+			Timeout: 10 * time.Second,
+		},
+		Journal: JournalConfig{
+			Retention: 20,
+		},
+	}
+
+	// The default networks are always valid
+	cfg.resolve()
+	return cfg
+}
+
+// Load returns the default configuration overridden by the given YAML file.
+// A missing file is only an error when it is not the default path.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && path == DefaultPath {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("failed to read configuration: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("invalid configuration %s: %w", path, err)
+	}
+	if err := cfg.migrateLegacy(data); err != nil {
+		return nil, fmt.Errorf("invalid configuration %s: %w", path, err)
+	}
+
+	if err := cfg.resolve(); err != nil {
+		return nil, fmt.Errorf("invalid configuration %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// resolve builds the address plans of the configured networks
+func (c *Config) resolve() error {
+	plan, err := ipam.New(c.Network.Parent, c.Network.TeamPrefix)
+	if err != nil {
+		return fmt.Errorf("network: %w", err)
+	}
+	if c.Network.IPv6 != "" {
+		if err := plan.EnableIPv6(c.Network.IPv6); err != nil {
+			return fmt.Errorf("network: %w", err)
+		}
+	}
+	gamePlan, err := ipam.New(c.Game.Subnet, c.Game.TeamPrefix)
+	if err != nil {
+		return fmt.Errorf("game: %w", err)
+	}
+
+	c.plan, c.gamePlan = plan, gamePlan
+	return nil
+}
+
+// IPAM returns the address plan of team networks
+func (c *Config) IPAM() *ipam.Plan {
+	return c.plan
+}
+
+// TeamAddressing returns the subnets of a team
+func (c *Config) TeamAddressing(teamID int) model.TeamAddressing {
+	addr := model.TeamAddressing{IPv4: c.plan.Team(teamID)}
+	if v6, ok := c.plan.Team6(teamID); ok {
+		addr.IPv6 = &v6
+	}
+	return addr
+}
+
+// GameNetwork returns the shared game network, without its vulnbox
+func (c *Config) GameNetwork() *model.GameNetwork {
+	return &model.GameNetwork{
+		Name: c.Game.Network,
+		Plan: c.gamePlan,
 	}
 }
 
@@ -87,6 +364,105 @@ func (c *Config) Validate() error {
 			c.Teams.MinID, c.Teams.MaxID)
 	}
 
+	// Validate the address plan fits every team and challenge
+	if c.Teams.MaxID >= c.plan.Teams() {
+		return fmt.Errorf("team ID %d does not fit in %s: /%d subnets leave room for IDs up to %d",
+			c.Teams.MaxID, c.plan.Parent(), c.Network.TeamPrefix, c.plan.Teams()-1)
+	}
+	if c.Challenges.MaxNetworkID > c.plan.MaxHost() {
+		return fmt.Errorf("challenge network ID %d does not fit in a /%d team subnet (max %d)",
+			c.Challenges.MaxNetworkID, c.Network.TeamPrefix, c.plan.MaxHost())
+	}
+	if c.Teams.BaseVPNPort+c.Teams.MaxID > 65535 {
+		return fmt.Errorf("VPN port of team ID %d exceeds 65535", c.Teams.MaxID)
+	}
+
+	if c.VPNAddress == "" {
+		return fmt.Errorf("vpn_address cannot be empty")
+	}
+
+	// Validate host inventory
+	for i, host := range c.Hosts {
+		if err := model.ValidateName(host.Name); err != nil {
+			return fmt.Errorf("invalid host: %w", err)
+		}
+		if host.Endpoint == "" || host.Address == "" {
+			return fmt.Errorf("host %s needs an endpoint and an address", host.Name)
+		}
+		if host.Weight < 0 {
+			return fmt.Errorf("host %s has a negative weight", host.Name)
+		}
+		if c.Host(host.Name) != &c.Hosts[i] {
+			return fmt.Errorf("host %s is declared twice", host.Name)
+		}
+	}
+	if len(c.Hosts) > 0 && c.Mode != ModeJeopardy {
+		return fmt.Errorf("the game network lives on a single host, %s mode cannot use hosts", c.Mode)
+	}
+
+	// Validate event mode
+	switch c.Mode {
+	case ModeJeopardy:
+	case ModeAttackDefense, ModeKingOfTheHill:
+		if c.gamePlan.Overlaps(c.plan.Parent()) {
+			return fmt.Errorf("game network %s overlaps team networks %s", c.gamePlan.Parent(), c.plan.Parent())
+		}
+		if c.Teams.MaxID >= c.gamePlan.Teams() {
+			return fmt.Errorf("team ID %d does not fit in game network %s: /%d ranges leave room for IDs up to %d",
+				c.Teams.MaxID, c.gamePlan.Parent(), c.Game.TeamPrefix, c.gamePlan.Teams()-1)
+		}
+	default:
+		return fmt.Errorf("invalid mode %q (must be %s, %s or %s)", c.Mode, ModeJeopardy, ModeAttackDefense, ModeKingOfTheHill)
+	}
+
+	// Validate deployment backend
+	switch c.Backend {
+	case BackendCompose:
+	case BackendKubernetes, BackendQuadlet:
+		if c.Mode != ModeJeopardy {
+			return fmt.Errorf("the %s backend only supports %s mode", c.Backend, ModeJeopardy)
+		}
+	default:
+		return fmt.Errorf("invalid backend %q (must be %s, %s or %s)", c.Backend, BackendCompose, BackendKubernetes, BackendQuadlet)
+	}
+
+	switch c.Mode {
+	case ModeAttackDefense:
+		if c.AttackDefense.Vulnbox == "" {
+			return fmt.Errorf("attack-defense mode requires attack_defense.vulnbox")
+		}
+		if c.AttackDefense.Tick <= 0 || c.AttackDefense.CheckerTimeout <= 0 {
+			return fmt.Errorf("attack-defense tick and checker timeout must be positive")
+		}
+		for _, svc := range c.AttackDefense.Services {
+			if svc.Name == "" || svc.Checker == "" {
+				return fmt.Errorf("attack-defense services need a name and a checker")
+			}
+		}
+	case ModeKingOfTheHill:
+		koth := c.KingOfTheHill
+		if koth.Challenge == "" {
+			return fmt.Errorf("king-of-the-hill mode requires king_of_the_hill.challenge")
+		}
+		if koth.Tick <= 0 {
+			return fmt.Errorf("king-of-the-hill tick must be positive")
+		}
+		if (koth.Ownership.File == "") == (koth.Ownership.Port == 0) {
+			return fmt.Errorf("king-of-the-hill ownership needs either a file or an HTTP port")
+		}
+	}
+
+	// Validate instancer limits
+	if c.Instancer.Listen != "" && (c.Instancer.TTL <= 0 || c.Instancer.MaxPerTeam <= 0) {
+		return fmt.Errorf("instancer ttl and max_per_team must be positive")
+	}
+
+	// Validate event timeline
+	if !c.Event.Start.IsZero() && !c.Event.End.IsZero() && !c.Event.Start.Before(c.Event.End) {
+		return fmt.Errorf("invalid event window: start %s is not before end %s",
+			c.Event.Start.Format(time.RFC3339), c.Event.End.Format(time.RFC3339))
+	}
+
 	return nil
 }
 
@@ -95,9 +471,31 @@ func (c *Config) GetChallengePath(challengeName string) string {
 	return filepath.Join(c.Paths.Challenges, challengeName)
 }
 
-// GetTeamPath returns the full path to a team directory
-func (c *Config) GetTeamPath(teamName string) string {
-	return filepath.Join(c.Paths.Teams, teamName)
+// GetTeamPath returns the full path to a team directory, given relative to the teams path
+func (c *Config) GetTeamPath(dir string) string {
+	return filepath.Join(c.Paths.Teams, dir)
+}
+
+// GetDataPath returns the full path to a file in the data directory
+func (c *Config) GetDataPath(name string) string {
+	return filepath.Join(c.Paths.Data, name)
+}
+
+// GetHostEndpoint returns the Docker endpoint of an inventory host, empty for the local daemon
+func (c *Config) GetHostEndpoint(host string) string {
+	if h := c.Host(host); h != nil {
+		return h.Endpoint
+	}
+	return ""
+}
+
+// GetVPNAddress returns the public address of the VPN endpoint of teams on a
+// host, VPNAddress for teams not on an inventory host
+func (c *Config) GetVPNAddress(host string) string {
+	if h := c.Host(host); h != nil {
+		return h.Address
+	}
+	return c.VPNAddress
 }
 
 // GetVPNPort returns the VPN port for a team
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"net/netip"
+
+	"gopkg.in/yaml.v3"
+)
+
+// legacyConfig holds the keys of earlier configuration formats, along with
+// the keys replacing them to detect files setting both
+type legacyConfig struct {
+	Network struct {
+		BaseSubnet string  `yaml:"base_subnet"` // Replaced by parent, e.g. "10.0" is 10.0.0.0/16 with /24 teams
+		Parent     *string `yaml:"parent"`
+		TeamPrefix *int    `yaml:"team_prefix"`
+	} `yaml:"network"`
+	Game struct {
+		BaseSubnet string  `yaml:"base_subnet"` // Replaced by subnet, like network.base_subnet
+		Network    *string `yaml:"network"`
+		Subnet     *string `yaml:"subnet"`
+		TeamPrefix *int    `yaml:"team_prefix"`
+	} `yaml:"game"`
+	AttackDefense struct {
+		Network    string `yaml:"network"`     // Moved to game.network
+		BaseSubnet string `yaml:"base_subnet"` // Moved to game.base_subnet, then game.subnet
+	} `yaml:"attack_defense"`
+}
+
+// migrateLegacy translates the keys of earlier configuration formats, so
+// existing events keep their addressing
+func (c *Config) migrateLegacy(data []byte) error {
+	var legacy legacyConfig
+	if err := yaml.Unmarshal(data, &legacy); err != nil {
+		return err
+	}
+
+	if base := legacy.Network.BaseSubnet; base != "" {
+		if legacy.Network.Parent != nil || legacy.Network.TeamPrefix != nil {
+			return fmt.Errorf("network.base_subnet is replaced by network.parent and network.team_prefix, remove it")
+		}
+		parent, err := legacySubnet(base)
+		if err != nil {
+			return fmt.Errorf("network.base_subnet: %w", err)
+		}
+		c.Network.Parent, c.Network.TeamPrefix = parent, 24
+	}
+
+	gameBase := legacy.Game.BaseSubnet
+	if ad := legacy.AttackDefense; ad.Network != "" || ad.BaseSubnet != "" {
+		if legacy.Game.Network != nil || legacy.Game.Subnet != nil || gameBase != "" {
+			return fmt.Errorf("attack_defense.network and attack_defense.base_subnet are replaced by game.network and game.subnet, remove them")
+		}
+		if ad.Network != "" {
+			c.Game.Network = ad.Network
+		}
+		gameBase = ad.BaseSubnet
+	}
+	if gameBase != "" {
+		if legacy.Game.Subnet != nil || legacy.Game.TeamPrefix != nil {
+			return fmt.Errorf("game.base_subnet is replaced by game.subnet and game.team_prefix, remove it")
+		}
+		subnet, err := legacySubnet(gameBase)
+		if err != nil {
+			return fmt.Errorf("game.base_subnet: %w", err)
+		}
+		c.Game.Subnet, c.Game.TeamPrefix = subnet, 24
+	}
+
+	return nil
+}
+
+// legacySubnet converts a base subnet of two octets, e.g. "172.16", into the /16 it denotes
+func legacySubnet(base string) (string, error) {
+	addr, err := netip.ParseAddr(base + ".0.0")
+	if err != nil || !addr.Is4() {
+		return "", fmt.Errorf("invalid base subnet %q, expected two octets such as \"10.0\"", base)
+	}
+	return netip.PrefixFrom(addr, 16).String(), nil
+}
@@ -0,0 +1,193 @@
+// Package docker drives the Docker daemon for team compose projects.
+//
+// Operations go through the docker CLI rather than the Engine API: team stacks
+// are compose projects, and recreating a service with the addressing declared
+// in its compose file is a compose feature the Engine API does not offer.
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/log"
+)
+
+// Client runs Docker operations through the docker CLI
+type Client struct {
+	binary   string
+	endpoint string // Docker daemon to drive, empty for the local one
+	logger   *log.Logger
+}
+
+// New creates a new Docker client
+func New(logger *log.Logger) *Client {
+	return &Client{
+		binary: "docker",
+		logger: logger,
+	}
+}
+
+// On returns a client driving the Docker daemon at an endpoint, e.g.
+// "ssh://ctf@node1"; an empty endpoint is the local daemon
+func (c *Client) On(endpoint string) *Client {
+	remote := *c
+	remote.endpoint = endpoint
+	return &remote
+}
+
+// RecreateService throws away the container of a compose service and starts a
+// fresh one from its image, keeping the addressing declared in the compose file
+func (c *Client) RecreateService(ctx context.Context, projectDir string, service string) error {
+	_, err := c.compose(ctx, projectDir,
+		"up", "--detach", "--no-deps", "--force-recreate", "--renew-anon-volumes", service)
+	if err != nil {
+		return fmt.Errorf("failed to recreate service %s: %w", service, err)
+	}
+	return nil
+}
+
+// Up creates or updates the containers of a compose project. Without services,
+// the whole project is deployed and containers of removed services are dropped.
+func (c *Client) Up(ctx context.Context, projectDir string, services ...string) error {
+	args := []string{"up", "--detach"}
+	if len(services) == 0 {
+		args = append(args, "--remove-orphans")
+	}
+	if _, err := c.compose(ctx, projectDir, append(args, services...)...); err != nil {
+		return fmt.Errorf("failed to start project %s: %w", filepath.Base(projectDir), err)
+	}
+	return nil
+}
+
+// Stop stops the containers of a compose project, or only the given services
+func (c *Client) Stop(ctx context.Context, projectDir string, services ...string) error {
+	if _, err := c.compose(ctx, projectDir, append([]string{"stop"}, services...)...); err != nil {
+		return fmt.Errorf("failed to stop project %s: %w", filepath.Base(projectDir), err)
+	}
+	return nil
+}
+
+// Remove stops and removes the containers of services of a compose project, with their anonymous volumes
+func (c *Client) Remove(ctx context.Context, projectDir string, services ...string) error {
+	args := append([]string{"rm", "--stop", "--force", "--volumes"}, services...)
+	if _, err := c.compose(ctx, projectDir, args...); err != nil {
+		return fmt.Errorf("failed to remove services of project %s: %w", filepath.Base(projectDir), err)
+	}
+	return nil
+}
+
+// RemoveContainer removes a container by name with its anonymous volumes, if it exists
+func (c *Client) RemoveContainer(ctx context.Context, name string) error {
+	if _, err := c.run(ctx, "rm", "--force", "--volumes", name); err != nil {
+		return fmt.Errorf("failed to remove container %s: %w", name, err)
+	}
+	return nil
+}
+
+// Down stops and removes the containers, networks and volumes of a compose project
+func (c *Client) Down(ctx context.Context, projectDir string) error {
+	if _, err := c.compose(ctx, projectDir, "down", "--volumes", "--remove-orphans"); err != nil {
+		return fmt.Errorf("failed to tear down project %s: %w", filepath.Base(projectDir), err)
+	}
+	return nil
+}
+
+// Exec runs a command in a running container and returns its output
+func (c *Client) Exec(ctx context.Context, container string, command ...string) (string, error) {
+	out, err := c.run(ctx, append([]string{"exec", container}, command...)...)
+	if err != nil {
+		return "", fmt.Errorf("failed to run command in %s: %w", container, err)
+	}
+	return out, nil
+}
+
+// EnsureNetwork creates a bridge network shared by compose projects if it does not exist
+func (c *Client) EnsureNetwork(ctx context.Context, name string, subnet string, gateway string) error {
+	if _, err := c.run(ctx, "network", "inspect", name); err == nil {
+		return nil
+	}
+
+	if _, err := c.run(ctx, "network", "create", "--driver", "bridge",
+		"--subnet", subnet, "--gateway", gateway, name); err != nil {
+		return fmt.Errorf("failed to create network %s: %w", name, err)
+	}
+
+	c.logger.Info("Network created", "name", name, "subnet", subnet)
+	return nil
+}
+
+// Subnets returns the subnets of every Docker network by network name
+func (c *Client) Subnets(ctx context.Context) (map[string][]string, error) {
+	out, err := c.run(ctx, "network", "ls", "--quiet")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list networks: %w", err)
+	}
+
+	subnets := make(map[string][]string)
+	ids := strings.Fields(out)
+	if len(ids) == 0 {
+		return subnets, nil
+	}
+
+	out, err = c.run(ctx, append([]string{"network", "inspect",
+		"--format", "{{.Name}}{{range .IPAM.Config}} {{.Subnet}}{{end}}"}, ids...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect networks: %w", err)
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 {
+			subnets[fields[0]] = fields[1:]
+		}
+	}
+	return subnets, nil
+}
+
+// Running returns whether each container, by name, is running
+func (c *Client) Running(ctx context.Context) (map[string]bool, error) {
+	out, err := c.run(ctx, "ps", "--all", "--format", "{{.Names}} {{.State}}")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	running := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if name, state, ok := strings.Cut(line, " "); ok {
+			running[name] = state == "running"
+		}
+	}
+	return running, nil
+}
+
+// compose runs a docker compose command against the compose.yml of a project directory
+func (c *Client) compose(ctx context.Context, projectDir string, args ...string) (string, error) {
+	base := []string{
+		"compose",
+		"--project-directory", projectDir,
+		"--file", filepath.Join(projectDir, "compose.yml"),
+	}
+	return c.run(ctx, append(base, args...)...)
+}
+
+func (c *Client) run(ctx context.Context, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	if c.endpoint != "" {
+		args = append([]string{"--host", c.endpoint}, args...)
+	}
+	cmd := exec.CommandContext(ctx, c.binary, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	c.logger.Debug("Running docker", "args", strings.Join(args, " "))
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
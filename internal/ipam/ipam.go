@@ -0,0 +1,187 @@
+// Package ipam carves team subnets out of a parent network and allocates
+// addresses in them deterministically
+package ipam
+
+import (
+	"fmt"
+	"math/big"
+	"net/netip"
+)
+
+// Host offsets of the infrastructure services, counted back from the end of
+// a team subnet. In a /24 they are .252, .253 and .254.
+const (
+	gatewayFromEnd = 2
+	dnsFromEnd     = 3
+	vpnFromEnd     = 4
+)
+
+// IPv6TeamPrefix is the prefix length of IPv6 team subnets
+const IPv6TeamPrefix = 64
+
+// Plan divides a parent network into equally sized team subnets. Team N gets
+// the N-th subnet, so team 1 of 10.0.0.0/16 with /24 subnets is 10.0.1.0/24.
+type Plan struct {
+	parent     netip.Prefix
+	teamPrefix int
+	ipv6       netip.Prefix // Optional ULA prefix of /64 team subnets
+}
+
+// New creates an address plan from a parent CIDR and the prefix length of team subnets
+func New(parent string, teamPrefix int) (*Plan, error) {
+	prefix, err := netip.ParsePrefix(parent)
+	if err != nil {
+		return nil, fmt.Errorf("invalid parent network: %w", err)
+	}
+	if prefix != prefix.Masked() {
+		return nil, fmt.Errorf("invalid parent network %s: host bits are set (use %s)", parent, prefix.Masked())
+	}
+	if teamPrefix < prefix.Bits() || teamPrefix > prefix.Addr().BitLen()-3 {
+		return nil, fmt.Errorf("invalid team prefix /%d for parent network %s", teamPrefix, parent)
+	}
+
+	return &Plan{parent: prefix, teamPrefix: teamPrefix}, nil
+}
+
+// EnableIPv6 adds a /64 IPv6 subnet to every team, carved out of a unique
+// local prefix such as fd00:c7f::/48
+func (p *Plan) EnableIPv6(parent string) error {
+	prefix, err := netip.ParsePrefix(parent)
+	if err != nil {
+		return fmt.Errorf("invalid IPv6 network: %w", err)
+	}
+	if !prefix.Addr().Is6() || prefix.Addr().Is4In6() || !prefix.Addr().IsPrivate() {
+		return fmt.Errorf("invalid IPv6 network %s: not a unique local prefix (fc00::/7)", parent)
+	}
+	if prefix != prefix.Masked() {
+		return fmt.Errorf("invalid IPv6 network %s: host bits are set (use %s)", parent, prefix.Masked())
+	}
+	if prefix.Bits() > IPv6TeamPrefix {
+		return fmt.Errorf("invalid IPv6 network %s: team subnets are /%d", parent, IPv6TeamPrefix)
+	}
+
+	p.ipv6 = prefix
+	return nil
+}
+
+// IPv6 returns the network IPv6 team subnets are carved from, and whether IPv6 is enabled
+func (p *Plan) IPv6() (netip.Prefix, bool) {
+	return p.ipv6, p.ipv6.IsValid()
+}
+
+// Parent returns the network team subnets are carved from
+func (p *Plan) Parent() netip.Prefix {
+	return p.parent
+}
+
+// Teams returns the number of team subnets in the parent networks
+func (p *Plan) Teams() int {
+	teams := count(p.teamPrefix - p.parent.Bits())
+	if p.ipv6.IsValid() {
+		teams = min(teams, count(IPv6TeamPrefix-p.ipv6.Bits()))
+	}
+	return teams
+}
+
+// MaxHost returns the highest host offset available to challenges in a team
+// subnet, below the infrastructure services
+func (p *Plan) MaxHost() int {
+	return p.subnetSize() - vpnFromEnd - 1
+}
+
+// Gateway returns the last usable address of the parent network, used as the
+// gateway when the parent itself is a single network
+func (p *Plan) Gateway() netip.Addr {
+	return offset(p.parent.Addr(), p.size(p.parent.Bits())-2)
+}
+
+// Team returns the subnet of a team
+func (p *Plan) Team(id int) Team {
+	return Team{
+		Subnet: subnet(p.parent, p.teamPrefix, id),
+		size:   p.subnetSize(),
+	}
+}
+
+// Team6 returns the IPv6 subnet of a team, whose services use the same host
+// offsets as in its IPv4 subnet, and false when IPv6 is disabled
+func (p *Plan) Team6(id int) (Team, bool) {
+	if !p.ipv6.IsValid() {
+		return Team{}, false
+	}
+	return Team{
+		Subnet: subnet(p.ipv6, IPv6TeamPrefix, id),
+		size:   p.subnetSize(),
+	}, true
+}
+
+// Overlaps reports whether a parent network overlaps another network
+func (p *Plan) Overlaps(other netip.Prefix) bool {
+	return p.parent.Overlaps(other) || p.ipv6.Overlaps(other)
+}
+
+func (p *Plan) subnetSize() int {
+	return p.size(p.teamPrefix)
+}
+
+func (p *Plan) size(bits int) int {
+	hostBits := p.parent.Addr().BitLen() - bits
+	if hostBits >= 62 {
+		return 1 << 62
+	}
+	return 1 << hostBits
+}
+
+// Team is the subnet of a team and the addresses of its services
+type Team struct {
+	Subnet netip.Prefix
+	size   int // Size of the IPv4 subnet, which infrastructure offsets count back from
+}
+
+// Host returns the address at an offset of the subnet, e.g. a challenge network ID
+func (t Team) Host(n int) netip.Addr {
+	return offset(t.Subnet.Addr(), n)
+}
+
+// Gateway returns the address of the host on the team network
+func (t Team) Gateway() netip.Addr {
+	return t.Host(t.size - gatewayFromEnd)
+}
+
+// DNS returns the address of the team resolver
+func (t Team) DNS() netip.Addr {
+	return t.Host(t.size - dnsFromEnd)
+}
+
+// VPN returns the address of the team WireGuard endpoint
+func (t Team) VPN() netip.Addr {
+	return t.Host(t.size - vpnFromEnd)
+}
+
+// count returns the number of subnets a number of prefix bits divides a network in
+func count(bits int) int {
+	if bits >= 31 {
+		return 1 << 31
+	}
+	return 1 << bits
+}
+
+// subnet returns the n-th subnet of a given prefix length in a network
+func subnet(parent netip.Prefix, bits int, n int) netip.Prefix {
+	step := new(big.Int).Lsh(big.NewInt(int64(n)), uint(parent.Addr().BitLen()-bits))
+	return netip.PrefixFrom(add(parent.Addr(), step), bits)
+}
+
+// offset returns the address n addresses after addr
+func offset(addr netip.Addr, n int) netip.Addr {
+	return add(addr, big.NewInt(int64(n)))
+}
+
+func add(addr netip.Addr, n *big.Int) netip.Addr {
+	sum := new(big.Int).Add(new(big.Int).SetBytes(addr.AsSlice()), n)
+
+	b := make([]byte, addr.BitLen()/8)
+	sum.FillBytes(b)
+	result, _ := netip.AddrFromSlice(b)
+	return result
+}
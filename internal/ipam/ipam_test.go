@@ -0,0 +1,103 @@
+package ipam
+
+import "testing"
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		parent     string
+		teamPrefix int
+	}{
+		{"not a CIDR", "10.0.0.0", 24},
+		{"not an address", "ten/16", 24},
+		{"host bits set", "10.0.1.0/16", 24},
+		{"team prefix shorter than parent", "10.0.0.0/16", 15},
+		{"team subnet too small", "10.0.0.0/16", 30},
+		{"negative team prefix", "10.0.0.0/16", -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.parent, tt.teamPrefix); err == nil {
+				t.Errorf("New(%q, %d) succeeded, want error", tt.parent, tt.teamPrefix)
+			}
+		})
+	}
+}
+
+func TestPlanTeam(t *testing.T) {
+	tests := []struct {
+		name       string
+		parent     string
+		teamPrefix int
+		id         int
+		subnet     string
+		host       string // Host 11, a challenge network ID
+		gateway    string
+		dns        string
+		vpn        string
+	}{
+		{"first /24", "10.0.0.0/16", 24, 1, "10.0.1.0/24", "10.0.1.11", "10.0.1.254", "10.0.1.253", "10.0.1.252"},
+		{"last /24", "10.0.0.0/16", 24, 255, "10.0.255.0/24", "10.0.255.11", "10.0.255.254", "10.0.255.253", "10.0.255.252"},
+		{"lower /25", "10.0.0.0/16", 25, 2, "10.0.1.0/25", "10.0.1.11", "10.0.1.126", "10.0.1.125", "10.0.1.124"},
+		{"upper /25", "10.0.0.0/16", 25, 3, "10.0.1.128/25", "10.0.1.139", "10.0.1.254", "10.0.1.253", "10.0.1.252"},
+		{"/25 of a /9", "10.128.0.0/9", 25, 1000, "10.129.244.0/25", "10.129.244.11", "10.129.244.126", "10.129.244.125", "10.129.244.124"},
+		{"upper /25 of a /9", "10.128.0.0/9", 25, 1001, "10.129.244.128/25", "10.129.244.139", "10.129.244.254", "10.129.244.253", "10.129.244.252"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := New(tt.parent, tt.teamPrefix)
+			if err != nil {
+				t.Fatalf("New(%q, %d) failed: %v", tt.parent, tt.teamPrefix, err)
+			}
+			team := plan.Team(tt.id)
+
+			for _, c := range []struct {
+				name string
+				got  string
+				want string
+			}{
+				{"Subnet", team.Subnet.String(), tt.subnet},
+				{"Host(11)", team.Host(11).String(), tt.host},
+				{"Gateway()", team.Gateway().String(), tt.gateway},
+				{"DNS()", team.DNS().String(), tt.dns},
+				{"VPN()", team.VPN().String(), tt.vpn},
+			} {
+				if c.got != c.want {
+					t.Errorf("Team(%d).%s = %s, want %s", tt.id, c.name, c.got, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPlanCapacity(t *testing.T) {
+	tests := []struct {
+		name       string
+		parent     string
+		teamPrefix int
+		teams      int
+		maxHost    int
+	}{
+		{"/24 of a /16", "10.0.0.0/16", 24, 256, 251},
+		{"/25 of a /16", "10.0.0.0/16", 25, 512, 123},
+		{"/25 of a /9", "10.128.0.0/9", 25, 65536, 123},
+		{"/29 of a /24", "10.0.0.0/24", 29, 32, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := New(tt.parent, tt.teamPrefix)
+			if err != nil {
+				t.Fatalf("New(%q, %d) failed: %v", tt.parent, tt.teamPrefix, err)
+			}
+			if got := plan.Teams(); got != tt.teams {
+				t.Errorf("Teams() = %d, want %d", got, tt.teams)
+			}
+			if got := plan.MaxHost(); got != tt.maxHost {
+				t.Errorf("MaxHost() = %d, want %d", got, tt.maxHost)
+			}
+		})
+	}
+}
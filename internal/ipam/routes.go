@@ -0,0 +1,67 @@
+package ipam
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// routeTable is the IPv4 routing table exposed by Linux
+const routeTable = "/proc/net/route"
+
+// Route is a network the host reaches through an interface
+type Route struct {
+	Interface string
+	Network   netip.Prefix
+}
+
+// HostRoutes returns the IPv4 routes of the host, without default routes
+func HostRoutes() ([]Route, error) {
+	file, err := os.Open(routeTable)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read routing table: %w", err)
+	}
+	defer file.Close()
+
+	var routes []Route
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // Header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 {
+			continue
+		}
+
+		dest, err1 := parseHex(fields[1])
+		mask, err2 := parseHex(fields[7])
+		if err1 != nil || err2 != nil || mask == 0 {
+			continue
+		}
+
+		var b [4]byte
+		binary.BigEndian.PutUint32(b[:], dest)
+		routes = append(routes, Route{
+			Interface: fields[0],
+			Network:   netip.PrefixFrom(netip.AddrFrom4(b), bits.OnesCount32(mask)).Masked(),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read routing table: %w", err)
+	}
+
+	return routes, nil
+}
+
+// parseHex decodes an address of the routing table, stored in host byte order
+func parseHex(s string) (uint32, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 4 {
+		return 0, fmt.Errorf("invalid address %q", s)
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
@@ -0,0 +1,40 @@
+package ipam
+
+import "testing"
+
+func TestParseHex(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    uint32
+		wantErr bool
+	}{
+		{"destination", "0001000A", 0x0A000100, false},
+		{"mask", "00FFFFFF", 0xFFFFFF00, false},
+		{"lowercase", "00ffffff", 0xFFFFFF00, false},
+		{"default route", "00000000", 0, false},
+		{"empty", "", 0, true},
+		{"odd length", "0001000", 0, true},
+		{"too short", "000100", 0, true},
+		{"too long", "0001000A00", 0, true},
+		{"not hex", "0001000G", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseHex(tt.s)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseHex(%q) = %#x, want error", tt.s, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseHex(%q) failed: %v", tt.s, err)
+			}
+			if got != tt.want {
+				t.Errorf("parseHex(%q) = %#x, want %#x", tt.s, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,310 @@
+// Package journal keeps what mutating operations remove so that they can be undone
+package journal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Lolozendev/CTFManager/internal/audit"
+)
+
+// DirName is the name of the journal directory in the data directory
+const DirName = "journal"
+
+// Trashed is a file or directory moved out of the way by an operation
+type Trashed struct {
+	Original string `json:"original"`
+	Trash    string `json:"trash"`
+}
+
+// Moved is a file or directory renamed by an operation
+type Moved struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Op is a journaled operation
+type Op struct {
+	ID      string          `json:"id"`
+	Time    time.Time       `json:"time"`
+	User    string          `json:"user"`
+	Command string          `json:"command"`
+	Action  string          `json:"action"`
+	State   json.RawMessage `json:"state"`             // State before the operation
+	Trashed []Trashed       `json:"trashed,omitempty"` // Paths moved to the trash, in order
+	Created []string        `json:"created,omitempty"` // Paths created by the operation
+	Moved   []Moved         `json:"moved,omitempty"`   // Paths renamed by the operation, in order
+
+	journal *Journal
+}
+
+// Journal stores the most recent operations in a directory
+type Journal struct {
+	dir       string
+	retention int
+}
+
+// New creates a journal in dir keeping the last retention operations
+func New(dir string, retention int) *Journal {
+	return &Journal{
+		dir:       dir,
+		retention: retention,
+	}
+}
+
+// Begin starts an operation, given the encoded state before it
+func (j *Journal) Begin(action string, state json.RawMessage) *Op {
+	now := time.Now()
+	return &Op{
+		ID:      now.Format("20060102-150405.000000000"),
+		Time:    now,
+		User:    audit.CurrentUser(),
+		Command: strings.Join(os.Args, " "),
+		Action:  action,
+		State:   state,
+		journal: j,
+	}
+}
+
+// Trash moves a path into the trash of the operation instead of deleting it
+func (op *Op) Trash(path string) error {
+	trash := filepath.Join(op.journal.trashDir(op.ID), fmt.Sprintf("%d-%s", len(op.Trashed), filepath.Base(path)))
+	if err := os.MkdirAll(filepath.Dir(trash), 0700); err != nil {
+		return fmt.Errorf("failed to create trash directory: %w", err)
+	}
+
+	if err := move(path, trash); err != nil {
+		return fmt.Errorf("failed to move %s to the trash: %w", path, err)
+	}
+
+	op.Trashed = append(op.Trashed, Trashed{Original: path, Trash: trash})
+	return nil
+}
+
+// Create records a path created by the operation, removed when it is undone
+func (op *Op) Create(path string) {
+	op.Created = append(op.Created, path)
+}
+
+// Move renames a path, moved back when the operation is undone
+func (op *Op) Move(from string, to string) error {
+	if _, err := os.Lstat(to); err == nil {
+		return fmt.Errorf("cannot move %s: %s already exists", from, to)
+	}
+
+	if err := move(from, to); err != nil {
+		return fmt.Errorf("failed to move %s to %s: %w", from, to, err)
+	}
+
+	op.Moved = append(op.Moved, Moved{From: from, To: to})
+	return nil
+}
+
+// Rollback undoes the filesystem changes of a failed operation
+func (op *Op) Rollback() error {
+	return op.revert()
+}
+
+// revert undoes the filesystem changes of the operation. Renames are undone
+// first so created and trashed paths are back at the location they were recorded at.
+func (op *Op) revert() error {
+	for i := len(op.Moved) - 1; i >= 0; i-- {
+		m := op.Moved[i]
+		if _, err := os.Lstat(m.From); err == nil {
+			return fmt.Errorf("cannot move back %s: path already exists", m.From)
+		}
+		if err := move(m.To, m.From); err != nil {
+			return fmt.Errorf("failed to move back %s: %w", m.From, err)
+		}
+	}
+
+	for _, path := range op.Created {
+		if err := os.RemoveAll(path); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+	}
+
+	for i := len(op.Trashed) - 1; i >= 0; i-- {
+		t := op.Trashed[i]
+		if _, err := os.Lstat(t.Original); err == nil {
+			return fmt.Errorf("cannot restore %s: path already exists", t.Original)
+		}
+		if err := move(t.Trash, t.Original); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", t.Original, err)
+		}
+	}
+
+	os.RemoveAll(op.journal.trashDir(op.ID))
+	return nil
+}
+
+// Commit records a completed operation and prunes operations beyond the retention
+func (j *Journal) Commit(op *Op) error {
+	ops, err := j.List()
+	if err != nil {
+		return err
+	}
+
+	ops = append(ops, *op)
+	if j.retention > 0 && len(ops) > j.retention {
+		for _, old := range ops[:len(ops)-j.retention] {
+			os.RemoveAll(j.trashDir(old.ID))
+		}
+		ops = ops[len(ops)-j.retention:]
+	}
+
+	return j.save(ops)
+}
+
+// List returns the journaled operations, oldest first
+func (j *Journal) List() ([]Op, error) {
+	data, err := os.ReadFile(j.indexPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	var ops []Op
+	if err := json.Unmarshal(data, &ops); err != nil {
+		return nil, fmt.Errorf("invalid journal: %w", err)
+	}
+	for i := range ops {
+		ops[i].journal = j
+	}
+
+	return ops, nil
+}
+
+// Undo reverts the filesystem changes of the last n operations, newest first,
+// and removes them from the journal. It returns the reverted operations, even
+// when stopping on an error; the caller restores the State of the oldest one.
+func (j *Journal) Undo(n int) ([]Op, error) {
+	ops, err := j.List()
+	if err != nil {
+		return nil, err
+	}
+
+	if n <= 0 || n > len(ops) {
+		return nil, fmt.Errorf("cannot undo %d operation(s): %d in the journal", n, len(ops))
+	}
+
+	undone := ops[len(ops)-n:]
+	for i := len(undone) - 1; i >= 0; i-- {
+		if err := undone[i].revert(); err != nil {
+			// Keep the operations that were not reverted
+			j.save(ops[:len(ops)-n+i+1])
+			return undone[i+1:], fmt.Errorf("failed to undo %s: %w", undone[i].Action, err)
+		}
+	}
+
+	if err := j.save(ops[:len(ops)-n]); err != nil {
+		return nil, err
+	}
+
+	return undone, nil
+}
+
+func (j *Journal) save(ops []Op) error {
+	if ops == nil {
+		ops = []Op{}
+	}
+
+	data, err := json.MarshalIndent(ops, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode journal: %w", err)
+	}
+
+	if err := os.MkdirAll(j.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create journal directory: %w", err)
+	}
+
+	tmp := j.indexPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	return os.Rename(tmp, j.indexPath())
+}
+
+func (j *Journal) indexPath() string {
+	return filepath.Join(j.dir, "journal.json")
+}
+
+func (j *Journal) trashDir(id string) string {
+	return filepath.Join(j.dir, "trash", id)
+}
+
+// move renames a path, copying it when source and destination are on different filesystems
+func move(src string, dst string) error {
+	err := os.Rename(src, dst)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	if err := copyTree(src, dst); err != nil {
+		os.RemoveAll(dst)
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+func copyTree(src string, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		default:
+			return nil
+		}
+	})
+}
+
+func copyFile(src string, dst string, mode fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,19 @@
+//go:build !unix
+
+package lock
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// Advisory locking is only implemented on Unix systems. Running without it
+// would let concurrent commands assign the same IDs, so locking fails instead.
+func tryLock(file *os.File) error {
+	return fmt.Errorf("advisory locking is not supported on %s", runtime.GOOS)
+}
+
+func unlock(file *os.File) error {
+	return nil
+}
@@ -0,0 +1,154 @@
+// Package lock serializes mutating operations with an advisory lock on the data directory
+package lock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileName is the name of the lock file in the data directory
+const FileName = "ctfmanager.lock"
+
+// retryInterval is the delay between two attempts to take a busy lock
+const retryInterval = 100 * time.Millisecond
+
+// errWouldBlock is returned by tryLock when another process holds the lock
+var errWouldBlock = errors.New("lock is held by another process")
+
+// Holder describes the process holding the lock
+type Holder struct {
+	PID     int       `json:"pid"`
+	Command string    `json:"command"`
+	Since   time.Time `json:"since"`
+}
+
+// TimeoutError is returned when the lock could not be acquired in time
+type TimeoutError struct {
+	Holder *Holder // nil when the holder is unknown
+}
+
+func (e *TimeoutError) Error() string {
+	if e.Holder == nil {
+		return "data directory is locked by another ctfmanager process"
+	}
+	return fmt.Sprintf("data directory is locked by PID %d (%s) since %s",
+		e.Holder.PID, e.Holder.Command, e.Holder.Since.Format(time.DateTime))
+}
+
+// Locker acquires the lock file of a data directory
+type Locker struct {
+	path    string
+	timeout time.Duration
+}
+
+// Lock is an acquired lock
+type Lock struct {
+	file *os.File
+}
+
+// New creates a locker for the given lock file, waiting at most timeout for a busy lock
+func New(path string, timeout time.Duration) *Locker {
+	return &Locker{
+		path:    path,
+		timeout: timeout,
+	}
+}
+
+// Acquire takes the lock, waiting for the current holder to release it
+func (l *Locker) Acquire() (*Lock, error) {
+	file, err := l.open()
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(l.timeout)
+	for {
+		err := tryLock(file)
+		if err == nil {
+			if l.current(file) {
+				break
+			}
+
+			// The lock file was replaced while waiting, e.g. by a restore
+			// swapping the data directory: take the new one
+			unlock(file)
+			file.Close()
+			if file, err = l.open(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if !errors.Is(err, errWouldBlock) {
+			file.Close()
+			return nil, fmt.Errorf("failed to lock data directory: %w", err)
+		}
+		if time.Now().After(deadline) {
+			file.Close()
+			return nil, &TimeoutError{Holder: readHolder(l.path)}
+		}
+		time.Sleep(retryInterval)
+	}
+
+	// Record who holds the lock for processes waiting on it
+	holder := Holder{
+		PID:     os.Getpid(),
+		Command: strings.Join(os.Args, " "),
+		Since:   time.Now(),
+	}
+	if data, err := json.Marshal(holder); err == nil {
+		file.Truncate(0)
+		file.WriteAt(data, 0)
+	}
+
+	return &Lock{file: file}, nil
+}
+
+func (l *Locker) open() (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	return file, nil
+}
+
+// current reports whether an open lock file is still the one at the lock path
+func (l *Locker) current(file *os.File) bool {
+	opened, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	onDisk, err := os.Stat(l.path)
+	return err == nil && os.SameFile(opened, onDisk)
+}
+
+// Release frees the lock
+func (l *Lock) Release() error {
+	l.file.Truncate(0)
+	if err := unlock(l.file); err != nil {
+		l.file.Close()
+		return fmt.Errorf("failed to unlock data directory: %w", err)
+	}
+	return l.file.Close()
+}
+
+func readHolder(path string) *Holder {
+	data, err := os.ReadFile(path)
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+
+	var holder Holder
+	if err := json.Unmarshal(data, &holder); err != nil {
+		return nil
+	}
+	return &holder
+}
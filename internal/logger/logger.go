@@ -1,42 +1,60 @@
 package logger
 
 import (
+	"fmt"
 	"io"
 	"os"
+	"strings"
 	"sync"
 
 	"github.com/charmbracelet/log"
 )
 
+// Log formats
+const (
+	FormatText   = "text"
+	FormatJSON   = "json"
+	FormatLogfmt = "logfmt"
+)
+
+// DefaultFile is the log file written when none is specified
+const DefaultFile = "ctfmanager.log"
+
 var (
 	instance *log.Logger
-	logFile  *os.File
+	logFile  io.WriteCloser
+	mu       sync.Mutex
 	once     sync.Once
 )
 
-// initLogger initializes the logger singleton
-func initLogger() {
-	var err error
-
-	// Open single log file
-	logFile, err = os.OpenFile(
-		"ctfmanager.log",
-		os.O_CREATE|os.O_WRONLY|os.O_APPEND,
-		0666,
-	)
-	if err != nil {
-		panic("Failed to open log file: " + err.Error())
+// Options configures the global logger
+type Options struct {
+	Level      string // debug, info, warn, error or fatal
+	Format     string // text, json or logfmt
+	File       string // Log file path, "" or "none" to only log to stderr
+	MaxSizeMB  int    // Rotate the log file when it reaches this size, 0 to disable rotation
+	MaxBackups int    // Number of rotated files to keep
+}
+
+// DefaultOptions returns the default logger options
+func DefaultOptions() Options {
+	return Options{
+		Level:      "info",
+		Format:     FormatText,
+		File:       DefaultFile,
+		MaxSizeMB:  10,
+		MaxBackups: 3,
 	}
+}
 
+// initLogger initializes the logger singleton, logging to stderr until Configure is called
+func initLogger() {
 	// Create logger with charmbracelet's pretty terminal output
 	instance = log.NewWithOptions(os.Stderr, log.Options{
 		ReportTimestamp: true,
 		TimeFormat:      "2006-01-02 15:04:05",
 		ReportCaller:    false,
 	})
-
-	// Write to both terminal (with colors) and file (plain text)
-	instance.SetOutput(io.MultiWriter(os.Stderr, logFile))
 }
 
 // Get returns the global logger instance
@@ -45,9 +63,65 @@ func Get() *log.Logger {
 	return instance
 }
 
+// Configure applies options to the global logger. When the log file cannot be
+// opened, logging falls back to stderr only and a warning is emitted.
+func Configure(opts Options) error {
+	logger := Get()
+
+	level, err := log.ParseLevel(opts.Level)
+	if err != nil {
+		return fmt.Errorf("invalid log level %q: %w", opts.Level, err)
+	}
+
+	var formatter log.Formatter
+	switch strings.ToLower(opts.Format) {
+	case FormatText, "":
+		formatter = log.TextFormatter
+	case FormatJSON:
+		formatter = log.JSONFormatter
+	case FormatLogfmt:
+		formatter = log.LogfmtFormatter
+	default:
+		return fmt.Errorf("invalid log format %q (expected: text, json or logfmt)", opts.Format)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if logFile != nil {
+		logFile.Close()
+		logFile = nil
+	}
+
+	logger.SetLevel(level)
+	logger.SetFormatter(formatter)
+
+	if opts.File == "" || opts.File == "none" {
+		// Only the terminal gets colors
+		logger.SetOutput(os.Stderr)
+		return nil
+	}
+
+	file, err := newRotatingFile(opts.File, int64(opts.MaxSizeMB)*1024*1024, opts.MaxBackups)
+	if err != nil {
+		logger.SetOutput(os.Stderr)
+		logger.Warn("Logging to stderr only", "error", err)
+		return nil
+	}
+	logFile = file
+
+	// Write to both terminal and file
+	logger.SetOutput(io.MultiWriter(os.Stderr, logFile))
+	return nil
+}
+
 // Close closes the log file - call this on graceful shutdown
 func Close() {
+	mu.Lock()
+	defer mu.Unlock()
+
 	if logFile != nil {
 		logFile.Close()
+		logFile = nil
 	}
 }
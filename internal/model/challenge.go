@@ -1,18 +1,54 @@
 package model
 
 import (
+	"encoding/json"
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/Lolozendev/CTFManager/internal/schedule"
 )
 
 // Challenge represents a CTF challenge
 type Challenge struct {
-	Name      string
-	NetworkID int    // Network position (11-249)
-	BuildPath string // Path to Dockerfile_test
-	EnvPath   string // Path to .env file
-	Enabled   bool
+	Name        string           `json:"name" yaml:"name"`
+	NetworkID   int              `json:"network_id" yaml:"network_id"` // Network position (11-249)
+	BuildPath   string           `json:"build_path" yaml:"build_path"` // Path to Dockerfile_test
+	EnvPath     string           `json:"env_path" yaml:"env_path"`     // Path to .env file
+	Enabled     bool             `json:"enabled" yaml:"enabled"`
+	Container   ContainerOptions `json:"container" yaml:"container"`     // Resource and hardening overrides from challenge.yml
+	Healthcheck *Healthcheck     `json:"healthcheck" yaml:"healthcheck"` // Liveness probe from challenge.yml
+	Reset       *ResetSchedule   `json:"reset" yaml:"reset"`             // Periodic reset schedule from challenge.yml
+	Release     *time.Time       `json:"release" yaml:"release"`         // Time at which the challenge is deployed to teams (nil: immediately)
+	OnDemand    bool             `json:"on_demand" yaml:"on_demand"`     // Started for a team by the instancer instead of on deploy
+	Ports       []string         `json:"ports" yaml:"ports"`             // Ports served to the team, e.g. "80" or "53/udp"
+}
+
+// Released reports whether the challenge is available to teams at the given time
+func (c Challenge) Released(now time.Time) bool {
+	return c.Release == nil || !now.Before(*c.Release)
+}
+
+// ChallengeSpec represents the optional challenge.yml file of a challenge directory
+type ChallengeSpec struct {
+	Container   ContainerOptions `yaml:"container"`
+	Healthcheck *Healthcheck     `yaml:"healthcheck"`
+	Reset       *ResetSchedule   `yaml:"reset"`
+	Release     *time.Time       `yaml:"release"`
+	OnDemand    bool             `yaml:"on_demand"`
+	Ports       []string         `yaml:"ports"`
+}
+
+var nameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// ValidateName checks that a team or challenge name can be used in container and network names
+func ValidateName(name string) error {
+	if !nameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid name %q (allowed: letters, digits, '_', '.' and '-', starting with a letter or digit)", name)
+	}
+	return nil
 }
 
 // ParseChallengeName parses a challenge directory name (format: "11-webchallenge" or "x-disabled")
@@ -47,3 +83,45 @@ func FormatChallengeName(networkID int, name string, enabled bool) string {
 	}
 	return fmt.Sprintf("%d-%s", networkID, name)
 }
+
+// ResetSchedule describes when a challenge is automatically reset for all teams
+type ResetSchedule struct {
+	Interval time.Duration `json:"interval,omitempty" yaml:"interval,omitempty"` // e.g. 30m
+	Cron     string        `json:"cron,omitempty" yaml:"cron,omitempty"`         // e.g. "*/15 * * * *"
+}
+
+// MarshalJSON writes the interval as in challenge.yml, e.g. "30m", instead of nanoseconds
+func (r ResetSchedule) MarshalJSON() ([]byte, error) {
+	type plain ResetSchedule
+	return json.Marshal(struct {
+		plain
+		Interval string `json:"interval,omitempty"`
+	}{plain(r), formatDuration(r.Interval)})
+}
+
+// Schedule returns the activation schedule described by the reset settings
+func (r ResetSchedule) Schedule() (schedule.Schedule, error) {
+	switch {
+	case r.Interval != 0 && r.Cron != "":
+		return nil, fmt.Errorf("reset schedule must set either interval or cron, not both")
+	case r.Interval < 0:
+		return nil, fmt.Errorf("invalid reset interval %s", r.Interval)
+	case r.Interval > 0:
+		if r.Interval < time.Minute {
+			return nil, fmt.Errorf("reset interval %s is too short (minimum 1m)", r.Interval)
+		}
+		return schedule.Every(r.Interval), nil
+	case r.Cron != "":
+		return schedule.ParseCron(r.Cron)
+	default:
+		return nil, fmt.Errorf("reset schedule must set an interval or a cron expression")
+	}
+}
+
+// String returns a readable description of the schedule
+func (r ResetSchedule) String() string {
+	if r.Cron != "" {
+		return "cron " + r.Cron
+	}
+	return "every " + r.Interval.String()
+}
@@ -0,0 +1,93 @@
+package model
+
+// ContainerOptions describes resource caps and hardening applied to a service.
+// Zero values mean "not set" so that per-challenge overrides can be merged
+// on top of the global defaults.
+type ContainerOptions struct {
+	CPUs              string   `json:"cpus,omitempty" yaml:"cpus,omitempty"`                             // e.g. "0.5"
+	Memory            string   `json:"memory,omitempty" yaml:"memory,omitempty"`                         // e.g. "256m"
+	MemoryReservation string   `json:"memory_reservation,omitempty" yaml:"memory_reservation,omitempty"` // soft limit
+	Pids              int      `json:"pids,omitempty" yaml:"pids,omitempty"`
+	Restart           string   `json:"restart,omitempty" yaml:"restart,omitempty"`
+	ReadOnly          *bool    `json:"read_only,omitempty" yaml:"read_only,omitempty"`
+	Tmpfs             []string `json:"tmpfs,omitempty" yaml:"tmpfs,omitempty"`
+	SecurityOpt       []string `json:"security_opt,omitempty" yaml:"security_opt,omitempty"`
+}
+
+// Merge returns a copy of o with every field set in override replacing the
+// corresponding value
+func (o ContainerOptions) Merge(override ContainerOptions) ContainerOptions {
+	merged := o
+
+	if override.CPUs != "" {
+		merged.CPUs = override.CPUs
+	}
+	if override.Memory != "" {
+		merged.Memory = override.Memory
+	}
+	if override.MemoryReservation != "" {
+		merged.MemoryReservation = override.MemoryReservation
+	}
+	if override.Pids != 0 {
+		merged.Pids = override.Pids
+	}
+	if override.Restart != "" {
+		merged.Restart = override.Restart
+	}
+	if override.ReadOnly != nil {
+		merged.ReadOnly = override.ReadOnly
+	}
+	if override.Tmpfs != nil {
+		merged.Tmpfs = override.Tmpfs
+	}
+	if override.SecurityOpt != nil {
+		merged.SecurityOpt = override.SecurityOpt
+	}
+
+	return merged
+}
+
+// Deploy represents the Docker Compose deploy section
+type Deploy struct {
+	Resources Resources `yaml:"resources"`
+}
+
+// Resources represents the resource limits and reservations of a service
+type Resources struct {
+	Limits       *ResourceSpec `yaml:"limits,omitempty"`
+	Reservations *ResourceSpec `yaml:"reservations,omitempty"`
+}
+
+// ResourceSpec represents a set of resource constraints
+type ResourceSpec struct {
+	CPUs   string `yaml:"cpus,omitempty"`
+	Memory string `yaml:"memory,omitempty"`
+	Pids   int    `yaml:"pids,omitempty"`
+}
+
+// ApplyContainerOptions sets the resource caps and hardening options on a service
+func (s *Service) ApplyContainerOptions(opts ContainerOptions) {
+	if opts.CPUs != "" || opts.Memory != "" || opts.Pids != 0 || opts.MemoryReservation != "" {
+		deploy := &Deploy{}
+		if opts.CPUs != "" || opts.Memory != "" || opts.Pids != 0 {
+			deploy.Resources.Limits = &ResourceSpec{
+				CPUs:   opts.CPUs,
+				Memory: opts.Memory,
+				Pids:   opts.Pids,
+			}
+		}
+		if opts.MemoryReservation != "" {
+			deploy.Resources.Reservations = &ResourceSpec{Memory: opts.MemoryReservation}
+		}
+		s.Deploy = deploy
+	}
+
+	if opts.Restart != "" {
+		s.Restart = opts.Restart
+	}
+	if opts.ReadOnly != nil {
+		s.ReadOnly = *opts.ReadOnly
+	}
+	s.Tmpfs = opts.Tmpfs
+	s.SecurityOpt = opts.SecurityOpt
+}
@@ -0,0 +1,144 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Healthcheck types
+const (
+	HealthcheckTCP  = "tcp"
+	HealthcheckHTTP = "http"
+	HealthcheckExec = "exec"
+)
+
+// Healthcheck describes how to verify that a challenge instance is alive
+type Healthcheck struct {
+	Type     string        `json:"type" yaml:"type"`                             // tcp, http or exec
+	Port     int           `json:"port,omitempty" yaml:"port,omitempty"`         // tcp and http
+	Path     string        `json:"path,omitempty" yaml:"path,omitempty"`         // http
+	Status   int           `json:"status,omitempty" yaml:"status,omitempty"`     // http, expected status code (default 200)
+	Body     string        `json:"body,omitempty" yaml:"body,omitempty"`         // http, expected substring of the response body
+	Command  string        `json:"command,omitempty" yaml:"command,omitempty"`   // exec, run with sh -c inside the container
+	Interval time.Duration `json:"interval,omitempty" yaml:"interval,omitempty"` // default 30s
+	Timeout  time.Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`   // default 5s
+	Retries  int           `json:"retries,omitempty" yaml:"retries,omitempty"`   // default 3
+}
+
+// MarshalJSON writes durations as in challenge.yml, e.g. "30s", instead of nanoseconds
+func (h Healthcheck) MarshalJSON() ([]byte, error) {
+	type plain Healthcheck
+	return json.Marshal(struct {
+		plain
+		Interval string `json:"interval,omitempty"`
+		Timeout  string `json:"timeout,omitempty"`
+	}{plain(h), formatDuration(h.Interval), formatDuration(h.Timeout)})
+}
+
+// formatDuration returns a duration such as "30s" or "1m30s", empty when zero
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	s := d.String()
+	// Drop the zero units time.Duration leaves behind, "30m0s" is "30m"
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// ComposeHealthcheck represents the Docker Compose healthcheck section
+type ComposeHealthcheck struct {
+	Test     []string `yaml:"test"`
+	Interval string   `yaml:"interval,omitempty"`
+	Timeout  string   `yaml:"timeout,omitempty"`
+	Retries  int      `yaml:"retries,omitempty"`
+}
+
+// Validate checks that the healthcheck has the fields required by its type
+func (h Healthcheck) Validate() error {
+	switch h.Type {
+	case HealthcheckTCP, HealthcheckHTTP:
+		if h.Port <= 0 || h.Port > 65535 {
+			return fmt.Errorf("%s healthcheck requires a valid port", h.Type)
+		}
+	case HealthcheckExec:
+		if h.Command == "" {
+			return fmt.Errorf("exec healthcheck requires a command")
+		}
+	default:
+		return fmt.Errorf("unknown healthcheck type %q (expected: tcp, http or exec)", h.Type)
+	}
+	return nil
+}
+
+// ExpectedStatus returns the HTTP status code expected by an http healthcheck
+func (h Healthcheck) ExpectedStatus() int {
+	if h.Status == 0 {
+		return 200
+	}
+	return h.Status
+}
+
+// ProbeTimeout returns the timeout of a single probe
+func (h Healthcheck) ProbeTimeout() time.Duration {
+	if h.Timeout == 0 {
+		return 5 * time.Second
+	}
+	return h.Timeout
+}
+
+// URL returns the URL probed by an http healthcheck on the given host
+func (h Healthcheck) URL(host string) string {
+	path := h.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return fmt.Sprintf("http://%s:%d%s", host, h.Port, path)
+}
+
+// ToCompose converts the healthcheck into its Docker Compose form, run from inside the container
+func (h Healthcheck) ToCompose() *ComposeHealthcheck {
+	var cmd string
+	switch h.Type {
+	case HealthcheckTCP:
+		cmd = fmt.Sprintf("nc -z 127.0.0.1 %d || exit 1", h.Port)
+	case HealthcheckHTTP:
+		// wget exits non-zero on error status codes
+		cmd = fmt.Sprintf("wget -q -O - %s", h.URL("127.0.0.1"))
+		if h.Body != "" {
+			cmd += fmt.Sprintf(" | grep -qF %s", shellQuote(h.Body))
+		}
+		cmd += " || exit 1"
+	case HealthcheckExec:
+		cmd = h.Command
+	default:
+		return nil
+	}
+
+	interval := h.Interval
+	if interval == 0 {
+		interval = 30 * time.Second
+	}
+	retries := h.Retries
+	if retries == 0 {
+		retries = 3
+	}
+
+	return &ComposeHealthcheck{
+		Test:     []string{"CMD-SHELL", cmd},
+		Interval: interval.String(),
+		Timeout:  h.ProbeTimeout().String(),
+		Retries:  retries,
+	}
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -1,9 +1,19 @@
 package model
 
+import (
+	"net/netip"
+	"strconv"
+
+	"github.com/Lolozendev/CTFManager/internal/ipam"
+)
+
 // Network represents Docker Compose network configuration
 type Network struct {
-	Driver string      `yaml:"driver"`
-	IPAM   NetworkIPAM `yaml:"ipam"`
+	Name       string      `yaml:"name,omitempty"`
+	External   bool        `yaml:"external,omitempty"`
+	Driver     string      `yaml:"driver,omitempty"`
+	EnableIPv6 bool        `yaml:"enable_ipv6,omitempty"`
+	IPAM       NetworkIPAM `yaml:"ipam,omitempty"`
 }
 
 // NetworkIPAM represents IP Address Management configuration
@@ -17,17 +27,119 @@ type NetworkConfig struct {
 	Gateway string `yaml:"gateway"`
 }
 
-// NewTeamNetwork creates a network for a team
-func NewTeamNetwork(teamNumber int) Network {
-	return Network{
+// VPNTunnel6 is the IPv6 prefix of the WireGuard tunnels when IPv6 is enabled,
+// shared by every team like the image's IPv4 tunnel subnet (10.13.13.0/24)
+const VPNTunnel6 = "fd13:13:13::"
+
+// Paths of the WireGuard configuration templates in a team directory, mounted
+// over those of the image when IPv6 is enabled
+const (
+	WireguardServerTemplate = "wireguard/server.conf"
+	WireguardPeerTemplate   = "wireguard/peer.conf"
+)
+
+// PeerTunnelIPv6 returns the IPv6 tunnel address of VPN peer n (from 1). Like
+// its IPv4 address 10.13.13.<n+1>, the last group is n+1 written in decimal,
+// as the peer template derives it from the IPv4 one.
+func PeerTunnelIPv6(n int) string {
+	return VPNTunnel6 + strconv.Itoa(n+1)
+}
+
+// TeamAddressing is the IPv4 subnet of a team and its optional IPv6 subnet
+type TeamAddressing struct {
+	IPv4 ipam.Team
+	IPv6 *ipam.Team // nil when IPv6 is disabled
+}
+
+// address returns the addresses of a host of the team network, the same
+// host in both subnets
+func (a TeamAddressing) address(host func(ipam.Team) netip.Addr) IPAddr {
+	addr := IPAddr{Ipv4Address: host(a.IPv4).String()}
+	if a.IPv6 != nil {
+		addr.Ipv6Address = host(*a.IPv6).String()
+	}
+	return addr
+}
+
+// AllowedIPs returns the subnets routed through the team VPN
+func (a TeamAddressing) AllowedIPs() string {
+	if a.IPv6 != nil {
+		return a.IPv4.Subnet.String() + "," + a.IPv6.Subnet.String()
+	}
+	return a.IPv4.Subnet.String()
+}
+
+// VPNAllowedIPs returns the subnets routed through the team VPN, with the game
+// network when there is one
+func VPNAllowedIPs(addr TeamAddressing, game *GameNetwork) string {
+	if game != nil {
+		return addr.AllowedIPs() + "," + game.Subnet()
+	}
+	return addr.AllowedIPs()
+}
+
+// NewTeamNetwork creates the network of a team on its subnets
+func NewTeamNetwork(addr TeamAddressing) Network {
+	network := Network{
 		Driver: "bridge",
 		IPAM: NetworkIPAM{
 			Config: []NetworkConfig{
 				{
-					Subnet:  formatStr("10.0.%d.0/24", teamNumber),
-					Gateway: formatStr("10.0.%d.254", teamNumber),
+					Subnet:  addr.IPv4.Subnet.String(),
+					Gateway: addr.IPv4.Gateway().String(),
 				},
 			},
 		},
 	}
+	if addr.IPv6 != nil {
+		network.EnableIPv6 = true
+		network.IPAM.Config = append(network.IPAM.Config, NetworkConfig{
+			Subnet:  addr.IPv6.Subnet.String(),
+			Gateway: addr.IPv6.Gateway().String(),
+		})
+	}
+	return network
+}
+
+// GameNetwork is the routed network shared by every team in attack-defense
+// and king-of-the-hill modes. Each team owns a range of it, e.g. 10.60.X.0/24.
+type GameNetwork struct {
+	Name    string     // Docker network name, created outside team projects
+	Plan    *ipam.Plan // Game subnet and the team ranges carved out of it
+	Vulnbox *Challenge // Deployed once per team, nil when not released yet
+}
+
+// Subnet returns the CIDR of the game network
+func (g GameNetwork) Subnet() string {
+	return g.Plan.Parent().String()
+}
+
+// Gateway returns the address of the host on the game network
+func (g GameNetwork) Gateway() string {
+	return g.Plan.Gateway().String()
+}
+
+// VPNIP returns the game network address of the WireGuard endpoint of a team
+func (g GameNetwork) VPNIP(teamNumber int) string {
+	return g.Plan.Team(teamNumber).VPN().String()
+}
+
+// VulnboxIP returns the game network address of the vulnbox of a team
+func (g GameNetwork) VulnboxIP(teamNumber int) string {
+	return g.Plan.Team(teamNumber).Host(1).String()
+}
+
+// HillIP returns the game network address of the king-of-the-hill challenge,
+// outside every team's range
+func (g GameNetwork) HillIP() string {
+	return g.Plan.Team(0).Host(1).String()
+}
+
+// Compose returns the compose declaration of the game network, which team
+// projects join but do not own
+func (g GameNetwork) Compose() Network {
+	return Network{
+		Name:     g.Name,
+		External: true,
+	}
 }
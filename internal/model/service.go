@@ -1,76 +1,152 @@
 package model
 
-import "fmt"
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/Lolozendev/CTFManager/internal/ipam"
+)
+
+// OnDemandProfile is the compose profile of challenges started by the
+// instancer, which a plain "docker compose up" leaves out
+const OnDemandProfile = "on-demand"
 
 // Service represents a Docker Compose service configuration
 type Service struct {
-	Image         string            `yaml:"image,omitempty"`
-	Build         string            `yaml:"build,omitempty"`
-	ContainerName string            `yaml:"container_name"`
-	Ports         []string          `yaml:"ports,omitempty"`
-	Environment   []string          `yaml:"environment,omitempty"`
-	Volumes       []string          `yaml:"volumes,omitempty"`
-	CapAdd        []string          `yaml:"cap_add,omitempty"`
-	EnvFile       string            `yaml:"env_file,omitempty"`
-	Networks      map[string]IPAddr `yaml:"networks"`
+	Image         string              `yaml:"image,omitempty"`
+	Build         string              `yaml:"build,omitempty"`
+	ContainerName string              `yaml:"container_name"`
+	Ports         []string            `yaml:"ports,omitempty"`
+	Expose        []string            `yaml:"expose,omitempty"`
+	Environment   []string            `yaml:"environment,omitempty"`
+	Volumes       []string            `yaml:"volumes,omitempty"`
+	CapAdd        []string            `yaml:"cap_add,omitempty"`
+	EnvFile       string              `yaml:"env_file,omitempty"`
+	Restart       string              `yaml:"restart,omitempty"`
+	ReadOnly      bool                `yaml:"read_only,omitempty"`
+	Tmpfs         []string            `yaml:"tmpfs,omitempty"`
+	SecurityOpt   []string            `yaml:"security_opt,omitempty"`
+	Deploy        *Deploy             `yaml:"deploy,omitempty"`
+	Healthcheck   *ComposeHealthcheck `yaml:"healthcheck,omitempty"`
+	Profiles      []string            `yaml:"profiles,omitempty"`
+	Sysctls       map[string]string   `yaml:"sysctls,omitempty"`
+	Networks      map[string]IPAddr   `yaml:"networks"`
 }
 
 // IPAddr represents network IP configuration
 type IPAddr struct {
 	Ipv4Address string `yaml:"ipv4_address"`
+	Ipv6Address string `yaml:"ipv6_address,omitempty"`
 }
 
-// NewWireguardService creates a Wireguard VPN service
-func NewWireguardService(teamName string, teamNumber int, memberCount int) Service {
+// NewWireguardService creates a Wireguard VPN service reached by clients at an address and host port
+func NewWireguardService(teamName string, addr TeamAddressing, serverURL string, port int, memberCount int) Service {
 	networkName := teamName + "-Network"
-	return Service{
+	service := Service{
 		Image:         "linuxserver/wireguard",
 		ContainerName: teamName + "-wireguard",
-		Ports:         []string{formatPort(50000 + teamNumber)},
+		Ports:         []string{formatPort(port)},
 		Environment: []string{
 			"PUID=1000",
 			"PGID=1000",
 			"TZ=Europe/Paris",
 			formatEnv("PEERS", memberCount),
-			formatEnv("PEERDNS", formatIP(teamNumber, 253)),
-			formatEnv("ALLOWEDIPS", formatSubnet(teamNumber)),
-			"SERVERURL=127.0.0.1", // TODO: Make configurable
-			formatEnv("SERVERPORT", 50000+teamNumber),
+			formatEnv("PEERDNS", addr.IPv4.DNS()),
+			formatEnv("ALLOWEDIPS", addr.AllowedIPs()),
+			formatEnv("SERVERURL", serverURL),
+			formatEnv("SERVERPORT", port),
 		},
 		Volumes: []string{"./config:/config"},
 		CapAdd:  []string{"NET_ADMIN"},
+		Restart: "unless-stopped",
 		Networks: map[string]IPAddr{
-			networkName: {Ipv4Address: formatIP(teamNumber, 252)},
+			networkName: addr.address(ipam.Team.VPN),
 		},
 	}
+	if addr.IPv6 != nil {
+		// Route IPv6 traffic from VPN clients to the team network
+		service.Sysctls = map[string]string{
+			"net.ipv6.conf.all.disable_ipv6": "0",
+			"net.ipv6.conf.all.forwarding":   "1",
+		}
+
+		// The image only addresses peers in IPv4: its configuration templates
+		// are replaced to give them a tunnel address in VPNTunnel6, routed back
+		// to each peer and masqueraded towards the team network
+		service.Volumes = append(service.Volumes,
+			"./"+WireguardServerTemplate+":/config/templates/server.conf:ro",
+			"./"+WireguardPeerTemplate+":/config/templates/peer.conf:ro",
+		)
+		for peer := 1; peer <= memberCount; peer++ {
+			service.Environment = append(service.Environment,
+				formatEnv(fmt.Sprintf("SERVER_ALLOWEDIPS_PEER_%d", peer), PeerTunnelIPv6(peer)+"/128"))
+		}
+	}
+	return service
 }
 
 // NewDnsmasqService creates a DNS service
-func NewDnsmasqService(teamName string, teamNumber int) Service {
+func NewDnsmasqService(teamName string, addr TeamAddressing) Service {
 	networkName := teamName + "-Network"
 	return Service{
 		Image:         "strm/dnsmasq",
 		ContainerName: teamName + "-dnsmasq",
 		Volumes:       []string{"./dns/dnsmasq.conf:/etc/dnsmasq.conf"},
+		Expose:        []string{"53/udp", "53/tcp"},
+		Restart:       "unless-stopped",
 		Networks: map[string]IPAddr{
-			networkName: {Ipv4Address: formatIP(teamNumber, 253)},
+			networkName: addr.address(ipam.Team.DNS),
 		},
 	}
 }
 
 // NewChallengeService creates a challenge service
-func NewChallengeService(teamName string, teamNumber int, challengeNumber int, challengeName string, buildPath string, envPath string) Service {
+func NewChallengeService(teamName string, addr TeamAddressing, challengeNumber int, challengeName string, buildPath string, envPath string) Service {
 	networkName := teamName + "-Network"
 	return Service{
 		Build:         buildPath,
 		ContainerName: teamName + "-" + challengeName,
 		EnvFile:       envPath,
 		Networks: map[string]IPAddr{
-			networkName: {Ipv4Address: formatIP(teamNumber, challengeNumber)},
+			networkName: addr.address(func(t ipam.Team) netip.Addr { return t.Host(challengeNumber) }),
 		},
 	}
 }
 
+// NewVulnboxService creates the vulnerable-services box of a team on the game network
+func NewVulnboxService(teamName string, teamNumber int, game GameNetwork) Service {
+	service := Service{
+		Build:         game.Vulnbox.BuildPath,
+		ContainerName: teamName + "-vulnbox",
+		EnvFile:       game.Vulnbox.EnvPath,
+		Networks: map[string]IPAddr{
+			game.Name: {Ipv4Address: game.VulnboxIP(teamNumber)},
+		},
+	}
+	service.ApplyContainerOptions(game.Vulnbox.Container)
+	service.Expose = game.Vulnbox.Ports
+	if game.Vulnbox.Healthcheck != nil {
+		service.Healthcheck = game.Vulnbox.Healthcheck.ToCompose()
+	}
+	return service
+}
+
+// ParsePort parses a port served by a service, e.g. "80" or "53/udp", into
+// its number and protocol
+func ParsePort(port string) (int, string, error) {
+	number, protocol, found := strings.Cut(port, "/")
+	if !found {
+		protocol = "tcp"
+	}
+	n, err := strconv.Atoi(number)
+	if err != nil || n <= 0 || n > 65535 || (protocol != "tcp" && protocol != "udp") {
+		return 0, "", fmt.Errorf("invalid port %q (expected: <port> or <port>/tcp|udp)", port)
+	}
+	return n, protocol, nil
+}
+
 // Helper functions
 func formatPort(port int) string {
 	return formatStr("%d:51820/udp", port)
@@ -80,14 +156,6 @@ func formatEnv(key string, value interface{}) string {
 	return formatStr("%s=%v", key, value)
 }
 
-func formatIP(teamNumber, host int) string {
-	return formatStr("10.0.%d.%d", teamNumber, host)
-}
-
-func formatSubnet(teamNumber int) string {
-	return formatStr("10.0.%d.0/24", teamNumber)
-}
-
 func formatStr(format string, args ...interface{}) string {
 	// Simple helper to avoid importing fmt in every function
 	return fmt.Sprintf(format, args...)
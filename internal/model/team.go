@@ -1,16 +1,25 @@
 package model
 
+import "strings"
+
 // Member represents a team member
 type Member struct {
-	Username string `json:"username"`
+	Username string `json:"username" yaml:"username"`
+}
+
+// TeamSpec represents the team.yml file written by earlier versions in team directories
+type TeamSpec struct {
+	Members []Member `yaml:"members"`
 }
 
 // Team represents a CTF team with its infrastructure
 type Team struct {
-	ID      int
-	Name    string
-	Members []Member
-	Enabled bool
+	ID      int      `json:"id" yaml:"id"`
+	Name    string   `json:"name" yaml:"name"`
+	Members []Member `json:"members" yaml:"members"`
+	Enabled bool     `json:"enabled" yaml:"enabled"`
+	Path    string   `json:"path" yaml:"path"`                     // Path to the team directory
+	Host    string   `json:"host,omitempty" yaml:"host,omitempty"` // Inventory host running the team
 }
 
 // ComposeFile represents a complete Docker Compose configuration
@@ -19,33 +28,92 @@ type ComposeFile struct {
 	Networks map[string]Network `yaml:"networks"`
 }
 
+// ComposeOptions defines where the services of a team compose file live
+type ComposeOptions struct {
+	Network TeamAddressing // Subnets of the team
+	VPNHost string         // Public address of the team WireGuard endpoint
+	VPNPort int            // Host port of the team WireGuard endpoint
+
+	// Game is the shared network the team's VPN routes to and its vulnbox
+	// lives on, in attack-defense and king-of-the-hill modes; nil for jeopardy events
+	Game *GameNetwork
+}
+
 // NewComposeFile creates a Docker Compose configuration for a team
-func NewComposeFile(team Team, challenges []Challenge) ComposeFile {
+func NewComposeFile(team Team, challenges []Challenge, opts ComposeOptions) ComposeFile {
 	networkName := team.Name + "-Network"
+	game := opts.Game
 
 	services := make(map[string]Service)
 
 	// Add infrastructure services
-	services["wireguard"] = NewWireguardService(team.Name, team.ID, len(team.Members))
-	services["dnsmasq"] = NewDnsmasqService(team.Name, team.ID)
+	wireguard := NewWireguardService(team.Name, opts.Network, opts.VPNHost, opts.VPNPort, len(team.Members))
+	services["dnsmasq"] = NewDnsmasqService(team.Name, opts.Network)
 
 	// Add challenge services
 	for _, challenge := range challenges {
-		services[challenge.Name] = NewChallengeService(
+		service := NewChallengeService(
 			team.Name,
-			team.ID,
+			opts.Network,
 			challenge.NetworkID,
 			challenge.Name,
 			challenge.BuildPath,
 			challenge.EnvPath,
 		)
+		service.ApplyContainerOptions(challenge.Container)
+		service.Expose = challenge.Ports
+		if challenge.Healthcheck != nil {
+			service.Healthcheck = challenge.Healthcheck.ToCompose()
+		}
+		if challenge.OnDemand {
+			service.Profiles = []string{OnDemandProfile}
+		}
+		services[challenge.Name] = service
 	}
 
 	networks := make(map[string]Network)
-	networks[networkName] = NewTeamNetwork(team.ID)
+	networks[networkName] = NewTeamNetwork(opts.Network)
+
+	if game != nil {
+		// Route VPN clients to every team's vulnbox through the game network
+		wireguard.Networks[game.Name] = IPAddr{Ipv4Address: game.VPNIP(team.ID)}
+		for i, env := range wireguard.Environment {
+			if strings.HasPrefix(env, "ALLOWEDIPS=") {
+				wireguard.Environment[i] = formatEnv("ALLOWEDIPS", VPNAllowedIPs(opts.Network, game))
+			}
+		}
+
+		if game.Vulnbox != nil {
+			services["vulnbox"] = NewVulnboxService(team.Name, team.ID, *game)
+		}
+		networks[game.Name] = game.Compose()
+	}
+	services["wireguard"] = wireguard
 
 	return ComposeFile{
 		Services: services,
 		Networks: networks,
 	}
 }
+
+// NewHillComposeFile creates the Docker Compose configuration of a challenge
+// deployed once on the game network and contested by every team
+func NewHillComposeFile(hill Challenge, game GameNetwork) ComposeFile {
+	service := Service{
+		Build:         hill.BuildPath,
+		ContainerName: "koth-" + hill.Name,
+		EnvFile:       hill.EnvPath,
+		Networks: map[string]IPAddr{
+			game.Name: {Ipv4Address: game.HillIP()},
+		},
+	}
+	service.ApplyContainerOptions(hill.Container)
+	if hill.Healthcheck != nil {
+		service.Healthcheck = hill.Healthcheck.ToCompose()
+	}
+
+	return ComposeFile{
+		Services: map[string]Service{hill.Name: service},
+		Networks: map[string]Network{game.Name: game.Compose()},
+	}
+}
@@ -0,0 +1,119 @@
+// Package output renders command results as tables or machine-readable documents
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is an output format
+type Format string
+
+// Supported output formats
+const (
+	FormatTable Format = "table"
+	FormatWide  Format = "wide"
+	FormatJSON  Format = "json"
+	FormatYAML  Format = "yaml"
+)
+
+// Formats lists the supported output formats
+var Formats = []Format{FormatTable, FormatWide, FormatJSON, FormatYAML}
+
+// ParseFormat validates an output format name
+func ParseFormat(name string) (Format, error) {
+	for _, f := range Formats {
+		if Format(strings.ToLower(name)) == f {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("invalid output format %q (expected: table, wide, json or yaml)", name)
+}
+
+// Structured reports whether the format is meant for programs rather than humans
+func (f Format) Structured() bool {
+	return f == FormatJSON || f == FormatYAML
+}
+
+// Table is the human-readable rendering of a result
+type Table struct {
+	Headers []string
+	Rows    [][]string
+}
+
+// TableFunc builds the table of a result, with extra columns when wide is set
+type TableFunc func(wide bool) Table
+
+// Print writes data in the given format. Structured formats serialize data
+// itself; table formats render the table built by table.
+func Print(w io.Writer, format Format, data any, table TableFunc) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(data)
+	case FormatYAML:
+		// Go through JSON so that both formats share the same field names
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		var doc any
+		if err := dec.Decode(&doc); err != nil {
+			return err
+		}
+
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(numbers(doc)); err != nil {
+			return err
+		}
+		return enc.Close()
+	case FormatTable, FormatWide:
+		return PrintTable(w, table(format == FormatWide))
+	default:
+		return fmt.Errorf("unsupported output format %q", format)
+	}
+}
+
+// numbers converts the numbers of a decoded JSON document back to integers or
+// floats, so that integers are not written in exponent notation
+func numbers(doc any) any {
+	switch v := doc.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	case map[string]any:
+		for k, item := range v {
+			v[k] = numbers(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = numbers(item)
+		}
+	}
+	return doc
+}
+
+// PrintTable writes a table with aligned columns
+func PrintTable(w io.Writer, t Table) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(t.Headers, "\t"))
+	for _, row := range t.Rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,97 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseCronErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+	}{
+		{"too few fields", "* * * *"},
+		{"too many fields", "* * * * * *"},
+		{"not a number", "a * * * *"},
+		{"minute out of range", "60 * * * *"},
+		{"day of month zero", "0 0 0 * *"},
+		{"month out of range", "0 0 1 13 *"},
+		{"day of week out of range", "0 0 * * 8"},
+		{"reversed range", "0 10-5 * * *"},
+		{"zero step", "*/0 * * * *"},
+		{"30 February", "0 0 30 2 *"},
+		{"31st of 30-day months", "0 0 31 4,6,9,11 *"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseCron(tt.expr); err == nil {
+				t.Errorf("ParseCron(%q) succeeded, want error", tt.expr)
+			}
+		})
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		name  string
+		expr  string
+		after string
+		want  string
+	}{
+		{"every minute", "* * * * *", "2026-10-19 10:00", "2026-10-19 10:01"},
+		{"strictly after", "30 10 * * *", "2026-10-19 10:30", "2026-10-20 10:30"},
+		{"list", "0 8,20 * * *", "2026-10-19 09:00", "2026-10-19 20:00"},
+		{"range", "0 9-17 * * *", "2026-10-19 17:30", "2026-10-20 09:00"},
+		{"step", "*/15 * * * *", "2026-10-19 10:16", "2026-10-19 10:30"},
+		{"step from value", "5/20 * * * *", "2026-10-19 10:26", "2026-10-19 10:45"},
+		{"step over range", "0 8-18/5 * * *", "2026-10-19 14:00", "2026-10-19 18:00"},
+		{"next month", "0 0 1 * *", "2026-10-19 10:00", "2026-11-01 00:00"},
+		{"next year", "0 0 1 1 *", "2026-10-19 10:00", "2027-01-01 00:00"},
+		{"day of week", "0 12 * * 5", "2026-10-19 10:00", "2026-10-23 12:00"},
+		{"sunday as 7", "0 12 * * 7", "2026-10-19 10:00", "2026-10-25 12:00"},
+		{"sunday as 0", "0 12 * * 0", "2026-10-19 10:00", "2026-10-25 12:00"},
+		{"day of month or day of week, weekday first", "0 0 31 * 5", "2026-10-19 10:00", "2026-10-23 00:00"},
+		{"day of month or day of week, day first", "0 0 20 * 5", "2026-10-19 10:00", "2026-10-20 00:00"},
+		{"restricted day of week only", "0 0 * * 1", "2026-10-19 10:00", "2026-10-26 00:00"},
+		{"31st skips short months", "0 0 31 * *", "2026-11-01 00:00", "2026-12-31 00:00"},
+		{"29 February", "0 0 29 2 *", "2026-10-19 10:00", "2028-02-29 00:00"},
+		{"29 February after 2100", "0 0 29 2 *", "2096-03-01 00:00", "2104-02-29 00:00"},
+		{"30 February or a Monday of February", "0 0 30 2 1", "2026-10-19 10:00", "2027-02-01 00:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cron, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron(%q): %v", tt.expr, err)
+			}
+			if got, want := cron.Next(date(tt.after)), date(tt.want); !got.Equal(want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.after, got.Format("2006-01-02 15:04 Mon"), want.Format("2006-01-02 15:04 Mon"))
+			}
+		})
+	}
+}
+
+func TestCronNextImpossible(t *testing.T) {
+	// Built directly, ParseCron rejects it
+	cron := &Cron{minute: 1, hour: 1, dom: 1 << 30, month: 1 << 2, dowAny: true}
+	if got := cron.Next(date("2026-10-19 10:00")); !got.IsZero() {
+		t.Errorf("Next() = %s, want zero time", got)
+	}
+}
+
+func TestEvery(t *testing.T) {
+	after := date("2026-10-19 10:00")
+	if got, want := Every(90*time.Minute).Next(after), date("2026-10-19 11:30"); !got.Equal(want) {
+		t.Errorf("Next() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,231 @@
+// Package store persists teams, challenges and their ID assignments in a JSON state file
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Lolozendev/CTFManager/internal/config"
+	"github.com/Lolozendev/CTFManager/internal/journal"
+	"github.com/Lolozendev/CTFManager/internal/lock"
+	"github.com/Lolozendev/CTFManager/internal/model"
+)
+
+// FileName is the name of the state file in the data directory
+const FileName = "state.json"
+
+// Version is the current state file format version
+const Version = 1
+
+// TeamRecord is the persisted state of a team
+type TeamRecord struct {
+	ID      int            `json:"id"`
+	Name    string         `json:"name"`
+	Members []model.Member `json:"members"`
+	Enabled bool           `json:"enabled"`
+	Dir     string         `json:"dir"`            // Directory under the teams path, <host>/<name> for placed teams
+	Host    string         `json:"host,omitempty"` // Inventory host running the team, empty when running locally
+}
+
+// ChallengeRecord is the persisted state of a challenge
+type ChallengeRecord struct {
+	Name      string `json:"name"`
+	NetworkID int    `json:"network_id"`
+	Enabled   bool   `json:"enabled"`
+	Dir       string `json:"dir"` // Directory name under the challenges path
+}
+
+// State is the full persisted state of the event
+type State struct {
+	Version    int               `json:"version"`
+	Teams      []TeamRecord      `json:"teams"`
+	Challenges []ChallengeRecord `json:"challenges"`
+}
+
+// Team returns the team with the given name, or nil
+func (s *State) Team(name string) *TeamRecord {
+	for i := range s.Teams {
+		if s.Teams[i].Name == name {
+			return &s.Teams[i]
+		}
+	}
+	return nil
+}
+
+// RemoveTeam removes the team with the given name
+func (s *State) RemoveTeam(name string) {
+	for i := range s.Teams {
+		if s.Teams[i].Name == name {
+			s.Teams = append(s.Teams[:i], s.Teams[i+1:]...)
+			return
+		}
+	}
+}
+
+// Challenge returns the challenge with the given name, or nil
+func (s *State) Challenge(name string) *ChallengeRecord {
+	for i := range s.Challenges {
+		if s.Challenges[i].Name == name {
+			return &s.Challenges[i]
+		}
+	}
+	return nil
+}
+
+// Store reads and writes the state file
+type Store struct {
+	path    string
+	locker  *lock.Locker
+	journal *journal.Journal
+}
+
+// New creates a store backed by the given file. Updates are serialized with
+// other processes through locker and recorded in the journal.
+func New(path string, locker *lock.Locker, j *journal.Journal) *Store {
+	return &Store{
+		path:    path,
+		locker:  locker,
+		journal: j,
+	}
+}
+
+// FromConfig creates the store of the configured data directory
+func FromConfig(cfg *config.Config) *Store {
+	return New(
+		cfg.GetDataPath(FileName),
+		lock.New(cfg.GetDataPath(lock.FileName), cfg.Lock.Timeout),
+		journal.New(cfg.GetDataPath(journal.DirName), cfg.Journal.Retention),
+	)
+}
+
+// Path returns the location of the state file
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Exists reports whether the state file has been created
+func (s *Store) Exists() bool {
+	_, err := os.Stat(s.path)
+	return err == nil
+}
+
+// Load reads the state file. A missing file is an empty state.
+func (s *Store) Load() (*State, error) {
+	state := &State{Version: Version}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("invalid state file %s: %w", s.path, err)
+	}
+
+	if state.Version > Version {
+		return nil, fmt.Errorf("state file %s has version %d, this build supports up to %d",
+			s.path, state.Version, Version)
+	}
+
+	return state, nil
+}
+
+// Save atomically replaces the state file
+func (s *Store) Save(state *State) error {
+	state.Version = Version
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to replace state: %w", err)
+	}
+
+	return nil
+}
+
+// Lock takes the data directory lock, for operations spanning more than a state update
+func (s *Store) Lock() (*lock.Lock, error) {
+	return s.locker.Acquire()
+}
+
+// Update loads the state, applies fn and saves the result if fn succeeds. The
+// data directory lock is held for the whole operation, so fn may also safely
+// modify team and challenge directories. The operation is journaled under
+// action: fn moves what it removes to the trash through op so it can be undone.
+func (s *Store) Update(action string, fn func(state *State, op *journal.Op) error) error {
+	l, err := s.Lock()
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+
+	state, err := s.Load()
+	if err != nil {
+		return err
+	}
+
+	before, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+	op := s.journal.Begin(action, before)
+
+	if err := fn(state, op); err != nil {
+		if rollbackErr := op.Rollback(); rollbackErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rollbackErr)
+		}
+		return err
+	}
+
+	if err := s.Save(state); err != nil {
+		op.Rollback()
+		return err
+	}
+
+	return s.journal.Commit(op)
+}
+
+// History returns the journaled operations, oldest first
+func (s *Store) History() ([]journal.Op, error) {
+	return s.journal.List()
+}
+
+// Undo reverts the last n journaled operations and restores the state from before them
+func (s *Store) Undo(n int) ([]journal.Op, error) {
+	l, err := s.Lock()
+	if err != nil {
+		return nil, err
+	}
+	defer l.Release()
+
+	undone, undoErr := s.journal.Undo(n)
+	if len(undone) > 0 {
+		var state State
+		if err := json.Unmarshal(undone[0].State, &state); err != nil {
+			return undone, fmt.Errorf("invalid journaled state: %w", err)
+		}
+		if err := s.Save(&state); err != nil {
+			return undone, err
+		}
+	}
+
+	return undone, undoErr
+}